@@ -0,0 +1,140 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// splits ARCHIVE:MEMBER, a name that exists as a file is never split
+func splitMember(name string) (string, string) {
+	if _, err := os.Stat(name); err == nil {
+		return name, ""
+	}
+
+	for i := 0; i < len(name); i++ {
+		if name[i] != ':' {
+			continue
+		}
+		if st, err := os.Stat(name[:i]); err == nil && st.Mode().IsRegular() {
+			return name[:i], name[i+1:]
+		}
+	}
+	return name, ""
+}
+
+// member names are compared without a leading ./ or /
+func sameMember(a, b string) bool {
+	clean := func(s string) string {
+		return strings.TrimPrefix(path.Clean("/"+s), "/")
+	}
+	return clean(a) == clean(b)
+}
+
+// opens f as a zip archive, zip archives are read from the end so f has to be a regular file
+// zip members are compressed on their own, so --decompress never applies to them
+func openZip(f *os.File) *zip.Reader {
+	st, err := f.Stat()
+	if err != nil || !st.Mode().IsRegular() {
+		return nil
+	}
+	zr, err := zip.NewReader(f, st.Size())
+	if err != nil {
+		return nil
+	}
+	return zr
+}
+
+// dumps a member of a zip or tar archive, or lists the members with --list-members
+// a file that isn't a zip archive is read as tar, decompressed first with --decompress
+func hexxyArchive(f *os.File, member string, w io.Writer, color *Color) error {
+	if zr := openZip(f); zr != nil {
+		if opts.ListMembers {
+			listHeader(w)
+			for _, m := range zr.File {
+				offset, err := m.DataOffset()
+				if err != nil {
+					return err
+				}
+				listMember(w, offset, int64(m.UncompressedSize64), int64(m.CompressedSize64), m.Name)
+			}
+			return nil
+		}
+
+		for _, m := range zr.File {
+			if !sameMember(m.Name, member) {
+				continue
+			}
+			rc, err := m.Open()
+			if err != nil {
+				return fmt.Errorf("%s: %v", m.Name, err)
+			}
+			defer rc.Close()
+			return dumpMember(rc, m.Name, w, color)
+		}
+		return fmt.Errorf("%s: no member %s", f.Name(), member)
+	}
+
+	var r io.Reader = f
+	if opts.Decompress != "" {
+		r = newDecompressor(f, opts.Decompress)
+	}
+
+	// counts the bytes in front of every member
+	cr := &countingReader{r: r}
+	tr := tar.NewReader(cr)
+	if opts.ListMembers {
+		listHeader(w)
+	}
+
+	for first := true; ; first = false {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if first {
+				return fmt.Errorf("%s: not a zip or tar archive", f.Name())
+			}
+			return fmt.Errorf("%s: %v", f.Name(), err)
+		}
+
+		if opts.ListMembers {
+			listMember(w, cr.n, hdr.Size, hdr.Size, hdr.Name)
+		} else if sameMember(hdr.Name, member) {
+			return dumpMember(tr, hdr.Name, w, color)
+		}
+	}
+
+	if opts.ListMembers {
+		return nil
+	}
+	return fmt.Errorf("%s: no member %s", f.Name(), member)
+}
+
+// dumps a member, --seek and --len count from the start of the member
+func dumpMember(r io.Reader, name string, w io.Writer, color *Color) error {
+	if opts.Seek != -1 {
+		if _, err := io.CopyN(io.Discard, r, opts.Seek); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+	if opts.Len != -1 {
+		r = io.LimitReader(r, opts.Len)
+	}
+	return HexxyDump(r, w, name, color)
+}
+
+func listHeader(w io.Writer) {
+	fmt.Fprintf(w, "%10s %10s %10s  %s\n", "offset", "size", "compressed", "name")
+}
+
+// a line of --list-members, offset is where the data of the member starts in the archive
+func listMember(w io.Writer, offset, size, compressed int64, name string) {
+	fmt.Fprintf(w, "%#10x %10d %10d  %s\n", offset, size, compressed, name)
+}
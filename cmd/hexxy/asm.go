@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// directives used for each assembler dialect, %s is the label and %d a number
+type asmDialect struct {
+	section string
+	align   string
+	global  string
+	label   string
+	data    string
+	length  string
+	footer  string
+}
+
+var asmDialects = map[string]asmDialect{
+	"gas": {
+		section: "\t.section .rodata\n",
+		align:   "\t.balign %d\n",
+		global:  "\t.globl %s\n",
+		label:   "%s:\n",
+		data:    "\t.byte ",
+		length:  "\t.globl %[1]s_len\n\t.set %[1]s_len, %[2]d\n",
+	},
+	"nasm": {
+		section: "section .rodata\n",
+		align:   "align %d\n",
+		global:  "global %s\n",
+		label:   "%s:\n",
+		data:    "\tdb ",
+		length:  "%s_len equ %d\n",
+	},
+	"armasm": {
+		section: "\tAREA |.rodata|, DATA, READONLY\n",
+		align:   "\tALIGN %d\n",
+		global:  "\tEXPORT %s\n",
+		label:   "%s\n",
+		data:    "\tDCB ",
+		length:  "%s_len EQU %d\n",
+		footer:  "\tEND\n",
+	},
+}
+
+// AsmDump writes r as a labelled block of data directives for the dialect given with --asm
+// the label is derived from the filename the same way as the C include output
+func AsmDump(r io.Reader, w io.Writer, filename string) error {
+	dialect, ok := asmDialects[opts.Asm]
+	if !ok {
+		return fmt.Errorf("unknown assembler dialect %q", opts.Asm)
+	}
+
+	cols := 12
+	if opts.Columns != -1 {
+		cols = opts.Columns
+	}
+	if cols < 1 {
+		return fmt.Errorf("asm: bytes per line must be at least 1, got %d", cols)
+	}
+	if opts.Align != -1 && (opts.Align < 1 || opts.Align&(opts.Align-1) != 0) {
+		return fmt.Errorf("asm: alignment must be a power of two, got %d", opts.Align)
+	}
+
+	caps := ldigits
+	if opts.Upper {
+		caps = udigits
+	}
+
+	var (
+		name  = identifier(filename)
+		line  = make([]byte, cols)
+		char  = make([]byte, 4)
+		total int64
+		err   error
+		n     int
+	)
+
+	io.WriteString(w, dialect.section)
+	if opts.Align > 1 {
+		fmt.Fprintf(w, dialect.align, opts.Align)
+	}
+	fmt.Fprintf(w, dialect.global, name)
+	fmt.Fprintf(w, dialect.label, name)
+
+	r = bufio.NewReader(r)
+	for {
+		n, err = io.ReadFull(r, line)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+
+		io.WriteString(w, dialect.data)
+		for i := 0; i < n; i++ {
+			cfmtEncode(char, line[i:i+1], caps)
+			w.Write(char)
+			if i != n-1 {
+				w.Write(commaSpace)
+			}
+		}
+		w.Write(newLine)
+		total += int64(n)
+	}
+
+	fmt.Fprintf(w, dialect.length, name, total)
+	io.WriteString(w, dialect.footer)
+	return nil
+}
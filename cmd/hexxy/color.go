@@ -8,6 +8,7 @@ import (
 var GREY = []byte("\x1b[38;2;111;111;111m")
 var ESC = []byte{0x5c, 0x78, 0x31, 0x62, 0x5b}
 var CLEAR = []byte("\x1b[0m")
+var RED = []byte("\x1b[1;37;41m")
 
 // var CLEAR = []byte{0x5c, 0x78, 0x31, 0x62, 0x5b, 0x30, 0x6d}
 
@@ -15,6 +16,7 @@ type Color struct {
 	disable bool
 	values  [256]string
 	cvalues [256][]byte
+	bg      [256]bool // the color sets a background
 }
 
 // check for NO_COLOR env var and block color
@@ -28,9 +30,19 @@ func (c *Color) Compute() {
 	for i := 0; i < 256; i++ {
 		var fg, bg string
 
-		lowVis := i == 0 || (i >= 16 && i <= 20) || (i >= 232 && i <= 242)
+		// the gradient collapses to the nearest system colors
+		if colorDepth == depth16 {
+			s := style{fg: palette(uint8(i))}
+			if lowVisibility(i) {
+				s = style{bold: true, fg: palette(15), bg: palette(uint8(i))}
+			}
+			c.setStyle(i, s)
+			continue
+		}
+
+		c.bg[i] = lowVisibility(i)
 
-		if lowVis {
+		if lowVisibility(i) {
 			fg = WHITEB + "\x1b[38;5;" + "255" + "m"
 			bg = "\x1b[48;5;" + strconv.Itoa(int(i)) + "m"
 		} else {
@@ -42,6 +54,41 @@ func (c *Color) Compute() {
 	}
 }
 
+// dark colors that are hard to read as a foreground, these are drawn as a background instead
+func lowVisibility(i int) bool {
+	return i == 0 || (i >= 16 && i <= 20) || (i >= 232 && i <= 242)
+}
+
+// returns the RGB value of a color in the xterm 256 color palette
+func xtermRGB(i int) (r, g, b uint8) {
+	// the 16 system colors as xterm draws them
+	system := [16][3]uint8{
+		{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+		{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+		{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+		{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+	}
+
+	switch {
+	case i < 16:
+		return system[i][0], system[i][1], system[i][2]
+	case i < 232:
+		// 6x6x6 color cube
+		level := func(v int) uint8 {
+			if v == 0 {
+				return 0
+			}
+			return uint8(55 + v*40)
+		}
+		i -= 16
+		return level(i / 36), level(i / 6 % 6), level(i % 6)
+	default:
+		// grayscale ramp
+		v := uint8(8 + (i-232)*10)
+		return v, v, v
+	}
+}
+
 func (c *Color) Colorize(s string, clr byte) string {
 	const NOCOLOR = "\x1b[0m"
 	return c.values[clr] + s + NOCOLOR
@@ -0,0 +1,61 @@
+package main
+
+// collects a row of output and only emits an escape sequence when the color changes
+// so runs of bytes with the same color share a single escape and a single reset
+type colorWriter struct {
+	buf []byte
+	cur []byte // the escape sequence in effect, empty for the default color
+	bg  bool   // the escape in effect sets a background, so it shows on spaces
+}
+
+// reports whether a and b are the same escape sequence, the escapes of a Color
+// share their slices so it is enough to compare where they point
+func sameEscape(a, b []byte) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// switches to the color of esc, an empty esc resets to the default color
+func (c *colorWriter) set(esc []byte, bg bool) {
+	if sameEscape(esc, c.cur) {
+		return
+	}
+	if len(c.cur) > 0 {
+		c.buf = append(c.buf, CLEAR...)
+	}
+	c.buf = append(c.buf, esc...)
+	c.cur = esc
+	c.bg = bg && len(esc) > 0
+}
+
+// writes text in the color of esc
+func (c *colorWriter) write(esc []byte, bg bool, b []byte) {
+	c.set(esc, bg)
+	c.buf = append(c.buf, b...)
+}
+
+// writes text in the color of the byte v
+func (c *colorWriter) byteColor(color *Color, v byte, b []byte) {
+	c.write(color.cvalues[v], color.bg[v], b)
+}
+
+// writes text without a color of its own
+func (c *colorWriter) plain(b []byte) {
+	c.set(nil, false)
+	c.buf = append(c.buf, b...)
+}
+
+// writes the spaces between columns, a foreground color can stay in effect over them
+func (c *colorWriter) gap(b []byte) {
+	if c.bg {
+		c.set(nil, false)
+	}
+	c.buf = append(c.buf, b...)
+}
+
+// resets the color and hands out the finished row
+func (c *colorWriter) flush() []byte {
+	c.set(nil, false)
+	b := c.buf
+	c.buf = c.buf[:0]
+	return b
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// a megabyte of text with runs of random bytes and zeros, so colors change often
+func benchInput() []byte {
+	rnd := rand.New(rand.NewSource(1))
+	text := []byte("The quick brown fox jumps over the lazy dog.\n")
+
+	const size = 1 << 20
+	var b []byte
+	for len(b) < size {
+		switch rnd.Intn(3) {
+		case 0:
+			b = append(b, text...)
+		case 1:
+			run := make([]byte, rnd.Intn(64))
+			rnd.Read(run)
+			b = append(b, run...)
+		default:
+			b = append(b, make([]byte, rnd.Intn(64))...)
+		}
+	}
+	return b[:size]
+}
+
+// a dumper of the given type as the flags would set it up
+func newBenchDumper(tb testing.TB, typ int, color bool) *dumper {
+	dumpType, USE_COLOR = typ, color
+	tb.Cleanup(func() {
+		dumpType, USE_COLOR = dumpHex, false
+	})
+
+	c := &Color{disable: !color}
+	if err := c.LoadTheme(""); err != nil {
+		tb.Fatal(err)
+	}
+
+	d, err := newDumper("bench.bin", c)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return d
+}
+
+func benchmarkDump(b *testing.B, color bool) {
+	in := benchInput()
+	d := newBenchDumper(b, dumpHex, color)
+	b.SetBytes(int64(len(in)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		// every run starts over at the first row
+		if err := d.at(0).slice(in, io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDumpColor(b *testing.B) {
+	benchmarkDump(b, true)
+}
+
+func BenchmarkDumpPlain(b *testing.B) {
+	benchmarkDump(b, false)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// CSVDump writes r as comma (or tab) separated values with a header line
+// by default there is one record per dump row with every byte in its own column,
+// --per-byte writes one record per byte with its hex, decimal, character and category
+func CSVDump(d *dumper, r io.Reader, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if opts.Format == "tsv" {
+		cw.Comma = '\t'
+	}
+
+	var record []string
+	if opts.PerByte {
+		record = []string{"offset", "hex", "dec", "char", "category"}
+	} else {
+		record = append(record, "offset")
+		for i := 0; i < d.cols; i++ {
+			record = append(record, "b"+strconv.Itoa(i))
+		}
+		record = append(record, "ascii")
+	}
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+	fields := len(record)
+
+	err := d.rows(r, func(row *dumpRow) error {
+		if row.data == nil {
+			// a collapsed run is a * like in the hex dump
+			record = append(record[:0], strconv.FormatInt(row.offset, d.colFmt), "*")
+			for len(record) < fields {
+				record = append(record, "")
+			}
+			return cw.Write(record)
+		}
+
+		if opts.PerByte {
+			for i, v := range row.data {
+				var char string
+				if v > 0x1f && v < 0x7f {
+					char = string(rune(v))
+				}
+
+				record = append(record[:0],
+					strconv.FormatInt(row.offset+int64(i), d.colFmt),
+					string(d.tables.hex[v][:]),
+					strconv.Itoa(int(v)),
+					char,
+					categoryNames[byteCategory(v)],
+				)
+				if err := cw.Write(record); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		record = append(record[:0], strconv.FormatInt(row.offset, d.colFmt))
+		for i := 0; i < d.cols; i++ {
+			if i < len(row.data) {
+				record = append(record, string(d.tables.hex[row.data[i]][:]))
+			} else {
+				record = append(record, "")
+			}
+		}
+		record = append(record, row.text)
+		return cw.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
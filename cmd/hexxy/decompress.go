@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/flate"
+	"compress/gzip"
+	"compress/lzw"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// a compressed stream of the input, gzip members and zlib streams can follow each other
+type compressedStream struct {
+	kind       string
+	offset     int64 // where the stream starts in the decompressed output
+	compressed int64 // where the stream starts in the input
+}
+
+// decompresses the input for --decompress, one stream after the other
+type decompressor struct {
+	kind    string
+	src     *countingReader
+	br      *bufio.Reader
+	cur     io.Reader // the stream being read, nil between streams
+	done    bool
+	n       int64 // decompressed bytes read so far
+	left    int64 // bytes left until --len, -1 for no limit
+	streams []compressedStream
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func newDecompressor(r io.Reader, kind string) *decompressor {
+	src := &countingReader{r: r}
+	return &decompressor{kind: kind, src: src, br: bufio.NewReader(src), left: -1}
+}
+
+// wraps r for --decompress, --seek and --len count decompressed bytes
+func decompressInput(r io.Reader) (io.Reader, error) {
+	d := newDecompressor(r, opts.Decompress)
+	if opts.Seek != -1 {
+		if _, err := io.CopyN(io.Discard, d, opts.Seek); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+	}
+	d.left = opts.Len
+	return d, nil
+}
+
+// the compression format magic starts, or "" if it's none of them
+// raw deflate and lzw have no header and are never detected
+func detectCompression(magic []byte) string {
+	switch {
+	case len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		return "gzip"
+	case len(magic) >= 4 && string(magic[:3]) == "BZh" && magic[3] >= '1' && magic[3] <= '9':
+		return "bzip2"
+	case len(magic) >= 2 && magic[0]&0x0f == 8 && magic[0]>>4 <= 7 && (uint16(magic[0])<<8|uint16(magic[1]))%31 == 0:
+		// deflate with a window of up to 32K and a valid header checksum
+		return "zlib"
+	}
+	return ""
+}
+
+// text can start with a valid zlib header, so a zlib stream is only taken for one
+// when its first bytes inflate
+func inflates(br *bufio.Reader) bool {
+	b, _ := br.Peek(br.Size())
+	zr, err := zlib.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return false
+	}
+	var probe [64]byte
+	_, err = zr.Read(probe[:])
+	// the stream may just go on past the peeked bytes
+	return err == nil || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// starts reading the next stream, reports false at the end of the input
+func (d *decompressor) next() (bool, error) {
+	if d.done {
+		return false, nil
+	}
+
+	magic, err := d.br.Peek(4)
+	if len(magic) == 0 {
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+		return false, err
+	}
+
+	kind := d.kind
+	first := len(d.streams) == 0
+	detected := detectCompression(magic)
+	if detected == "zlib" && !inflates(d.br) {
+		detected = ""
+	}
+
+	if kind == "auto" {
+		kind = detected
+		if kind == "" && first {
+			// input that isn't compressed is dumped as it is
+			d.cur, d.done = d.br, true
+			return true, nil
+		}
+	}
+	if !first && (kind == "" || detected != kind) {
+		// data after the last stream is dumped as it is and marked as a raw stream
+		kind = "raw"
+	}
+
+	s := compressedStream{kind: kind, offset: d.n, compressed: d.src.n - int64(d.br.Buffered())}
+
+	switch kind {
+	case "gzip":
+		gz, err := gzip.NewReader(d.br)
+		if err != nil {
+			return false, fmt.Errorf("gzip stream at 0x%x: %v", s.compressed, err)
+		}
+		// stop after every member to find where the next one starts
+		gz.Multistream(false)
+		d.cur = gz
+	case "zlib":
+		zr, err := zlib.NewReader(d.br)
+		if err != nil {
+			return false, fmt.Errorf("zlib stream at 0x%x: %v", s.compressed, err)
+		}
+		d.cur = zr
+	case "bzip2":
+		// bzip2 reads concatenated streams by itself
+		d.cur, d.done = bzip2.NewReader(d.br), true
+	case "flate":
+		d.cur, d.done = flate.NewReader(d.br), true
+	case "lzw":
+		// the variant used by PDF and GIF, with 8 bit literals
+		d.cur, d.done = lzw.NewReader(d.br, lzw.MSB, 8), true
+	case "raw":
+		d.cur, d.done = d.br, true
+	}
+
+	d.streams = append(d.streams, s)
+	return true, nil
+}
+
+func (d *decompressor) Read(p []byte) (int, error) {
+	if d.left == 0 {
+		return 0, io.EOF
+	}
+	if d.left > 0 && int64(len(p)) > d.left {
+		p = p[:d.left]
+	}
+
+	for {
+		if d.cur == nil {
+			ok, err := d.next()
+			if err != nil {
+				return 0, err
+			}
+			if !ok {
+				return 0, io.EOF
+			}
+		}
+
+		n, err := d.cur.Read(p)
+		d.n += int64(n)
+		if d.left > 0 {
+			d.left -= int64(n)
+		}
+
+		if errors.Is(err, io.EOF) {
+			if c, ok := d.cur.(io.Closer); ok {
+				if err := c.Close(); err != nil {
+					return n, err
+				}
+			}
+			d.cur = nil
+			err = nil
+		} else if err != nil && len(d.streams) > 0 {
+			// also tells a truncated stream apart from the end of the input
+			s := d.streams[len(d.streams)-1]
+			err = fmt.Errorf("%s stream at 0x%x: %v", s.kind, s.compressed, err)
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// writes a line for every compressed stream that starts before end, for --mark-streams
+// * gzip stream 2 at 0x1f, compressed 0x33
+func (d *dumper) markStreams(end int64) {
+	if d.compressed == nil {
+		return
+	}
+
+	for ; d.mark < len(d.compressed.streams) && d.compressed.streams[d.mark].offset < end; d.mark++ {
+		s := d.compressed.streams[d.mark]
+		d.cw.plain(asterisk)
+		d.cw.buf = append(d.cw.buf, ' ')
+		d.cw.buf = append(d.cw.buf, s.kind...)
+		d.cw.buf = append(d.cw.buf, streamLine...)
+		d.cw.buf = strconv.AppendInt(d.cw.buf, int64(d.mark+1), 10)
+		d.cw.buf = append(d.cw.buf, " at "...)
+		d.appendOffset(s.offset)
+		d.cw.buf = append(d.cw.buf, compressedAt...)
+		d.appendOffset(s.compressed)
+		d.cw.buf = append(d.cw.buf, newLine...)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	depth16 = iota
+	depth256
+	depthTrue
+)
+
+// the number of colors escape sequences are downsampled to, set with --color-depth
+var colorDepth = depthTrue
+
+// picks the color depth for --color-depth, auto asks the terminal
+func setColorDepth(depth string) {
+	switch depth {
+	case "16":
+		colorDepth = depth16
+	case "256":
+		colorDepth = depth256
+	case "truecolor":
+		colorDepth = depthTrue
+	default:
+		colorDepth = detectColorDepth()
+	}
+
+	// the offset and other secondary text
+	switch colorDepth {
+	case depth16:
+		GREY = []byte("\x1b[90m")
+	case depth256:
+		GREY = []byte("\x1b[38;5;242m")
+	}
+}
+
+// checks COLORTERM, TERM and the terminfo database for the colors the terminal supports
+func detectColorDepth() int {
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return depthTrue
+	}
+
+	// windows terminal does not set TERM
+	if os.Getenv("WT_SESSION") != "" {
+		return depthTrue
+	}
+
+	term := os.Getenv("TERM")
+	switch {
+	case term == "" || term == "dumb":
+		// most likely a CI log or a serial console, stay safe
+		return depth16
+	case strings.HasSuffix(term, "-direct"), strings.Contains(term, "truecolor"):
+		return depthTrue
+	}
+
+	if colors, ok := terminfoColors(term); ok {
+		switch {
+		case colors >= 1<<24:
+			return depthTrue
+		case colors >= 256:
+			return depth256
+		default:
+			return depth16
+		}
+	}
+
+	if strings.Contains(term, "256color") {
+		return depth256
+	}
+	// an unknown terminal, nearly every emulator in use today handles 256 colors
+	return depth256
+}
+
+// reads the colors capability of a compiled terminfo entry
+func terminfoColors(term string) (int, bool) {
+	if term == "" || strings.ContainsAny(term, "/\\") {
+		return 0, false
+	}
+
+	var dirs []string
+	if dir := os.Getenv("TERMINFO"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".terminfo"))
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("TERMINFO_DIRS")) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	dirs = append(dirs, "/etc/terminfo", "/lib/terminfo", "/usr/share/terminfo", "/usr/lib/terminfo")
+
+	for _, dir := range dirs {
+		// entries live in a directory named after the first letter, or its hex value on macOS
+		for _, sub := range []string{term[:1], strconv.FormatInt(int64(term[0]), 16)} {
+			b, err := os.ReadFile(filepath.Join(dir, sub, term))
+			if err != nil {
+				continue
+			}
+			return parseTerminfoColors(b)
+		}
+	}
+	return 0, false
+}
+
+// the compiled terminfo format is described in term(5)
+func parseTerminfoColors(b []byte) (int, bool) {
+	const colorsIndex = 13
+
+	if len(b) < 12 {
+		return 0, false
+	}
+
+	le := binary.LittleEndian
+	numSize := 2
+	switch le.Uint16(b) {
+	case 0o432:
+	case 0o1036:
+		// the extended format with 32-bit numbers
+		numSize = 4
+	default:
+		return 0, false
+	}
+
+	names := int(le.Uint16(b[2:]))
+	bools := int(le.Uint16(b[4:]))
+	nums := int(le.Uint16(b[6:]))
+	if nums <= colorsIndex {
+		return 0, false
+	}
+
+	off := 12 + names + bools
+	// numbers start on an even byte
+	if off%2 == 1 {
+		off++
+	}
+	off += colorsIndex * numSize
+	if off+numSize > len(b) {
+		return 0, false
+	}
+
+	var colors int
+	if numSize == 2 {
+		colors = int(int16(le.Uint16(b[off:])))
+	} else {
+		colors = int(int32(le.Uint32(b[off:])))
+	}
+	if colors < 0 {
+		// the capability is absent
+		return 0, false
+	}
+	return colors, true
+}
+
+// the closest of the 16 system colors
+func nearest16(r, g, b uint8) uint8 {
+	best, bestDist := 0, -1
+	for i := 0; i < 16; i++ {
+		pr, pg, pb := xtermRGB(i)
+		dr, dg, db := int(r)-int(pr), int(g)-int(pg), int(b)-int(pb)
+		if d := dr*dr + dg*dg + db*db; bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return uint8(best)
+}
+
+// the closest color of the 6x6x6 cube or the grayscale ramp
+func nearest256(r, g, b uint8) uint8 {
+	// the cube levels are 0, 95, 135, 175, 215, 255
+	level := func(v uint8) int {
+		if v < 48 {
+			return 0
+		}
+		if v < 115 {
+			return 1
+		}
+		return (int(v) - 35) / 40
+	}
+
+	cube := 16 + 36*level(r) + 6*level(g) + level(b)
+
+	// the ramp goes from 8 to 238 in steps of 10
+	avg := (int(r) + int(g) + int(b)) / 3
+	gray := 232 + min(max((avg-3)/10, 0), 23)
+
+	dist := func(i int) int {
+		pr, pg, pb := xtermRGB(i)
+		dr, dg, db := int(r)-int(pr), int(g)-int(pg), int(b)-int(pb)
+		return dr*dr + dg*dg + db*db
+	}
+	if dist(gray) < dist(cube) {
+		return uint8(gray)
+	}
+	return uint8(cube)
+}
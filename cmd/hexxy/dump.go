@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// renders the rows of the hex, binary, C include and plain dumps
+// every row only depends on its offset and the dumper state, which is what lets
+// chunks of a file be rendered in parallel
+type dumper struct {
+	color     *Color
+	tables    *byteTables
+	text      *textColumn
+	cw        colorWriter // collects the output of a row
+	cols      int
+	octs      int
+	groupSize int
+	colFmt    int
+	start     int64 // offset of the first byte in the file, --seek
+	offset    int64 // offset of the next row from start
+	nulLine   int64 // empty rows in a row, for --autoskip
+	hexOffset []byte
+	holes     []hole // holes of a sparse file, relative to start
+	hole      int    // the first hole that may still be ahead
+
+	compressed *decompressor // the streams to mark for --mark-streams
+	mark       int           // the first stream that wasn't marked yet
+
+	varDeclChar []byte // "unsigned char NAME[] = {"
+	varDeclInt  []byte // "};\nunsigned int NAME_len = "
+}
+
+func newDumper(filename string, color *Color) (*dumper, error) {
+	d := &dumper{color: color}
+
+	caps := ldigits
+	if opts.Upper {
+		caps = udigits
+	}
+	d.tables = newByteTables(caps)
+
+	if dumpType == dumpCformat {
+		name := identifier(filename)
+		d.varDeclChar = append(append(append([]byte{}, unsignedChar...), name...), brackets...)
+		d.varDeclInt = append(append(append([]byte{}, unsignedInt...), name...), lenEquals...)
+	}
+
+	if opts.Columns == -1 {
+		switch dumpType {
+		case dumpPlain:
+			d.cols = 30
+		case dumpCformat:
+			d.cols = 12
+		case dumpBinary:
+			d.cols = 6
+		default:
+			d.cols = 16
+		}
+	} else {
+		d.cols = opts.Columns
+	}
+
+	switch dumpType {
+	case dumpBinary:
+		d.octs = 8
+		d.groupSize = 1
+	case dumpCformat:
+		d.octs = 4
+	default:
+		d.octs = 2
+		d.groupSize = 2
+	}
+
+	if opts.GroupSize != -1 {
+		d.groupSize = opts.GroupSize
+	}
+
+	if opts.Len != -1 {
+		if opts.Len < int64(d.cols) {
+			d.cols = int(opts.Len)
+		}
+	}
+
+	if d.cols < 1 {
+		if opts.Len == 0 {
+			// nothing will be read anyway
+			d.cols = 1
+		} else {
+			return nil, fmt.Errorf("column count must be at least 1, got %d", d.cols)
+		}
+	}
+
+	switch opts.OffsetFormat {
+	case "d":
+		d.colFmt = 10
+	case "o":
+		d.colFmt = 8
+	default:
+		d.colFmt = 16
+	}
+
+	if opts.Seek != -1 {
+		d.start = opts.Seek
+	}
+
+	var err error
+	d.text, err = newTextColumn(opts.Text, opts.Codepage, opts.Glyphs)
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// a copy with its own buffers that continues at offset, used to render a chunk
+// only the settings are copied, d may be writing at the same time
+func (d *dumper) at(offset int64) *dumper {
+	text := &textColumn{
+		decode: d.text.decode,
+		width:  d.text.width,
+		unit:   d.text.unit,
+		glyphs: d.text.glyphs,
+	}
+
+	return &dumper{
+		color:       d.color,
+		tables:      d.tables,
+		text:        text,
+		cols:        d.cols,
+		octs:        d.octs,
+		groupSize:   d.groupSize,
+		colFmt:      d.colFmt,
+		start:       d.start,
+		offset:      offset,
+		holes:       d.holes,
+		hole:        d.holeAfter(offset),
+		varDeclChar: d.varDeclChar,
+		varDeclInt:  d.varDeclInt,
+	}
+}
+
+// starts the dump, only the C include format has a header
+func (d *dumper) header() {
+	if dumpType == dumpCformat {
+		d.cw.buf = append(d.cw.buf, d.varDeclChar...)
+		d.cw.buf = append(d.cw.buf, newLine...)
+	}
+}
+
+// ends the dump after size bytes
+func (d *dumper) footer(size int64) {
+	switch dumpType {
+	case dumpPlain:
+		d.cw.buf = append(d.cw.buf, newLine...)
+	case dumpCformat:
+		d.cw.buf = append(d.cw.buf, d.varDeclInt...)
+		d.cw.buf = strconv.AppendInt(d.cw.buf, size, 10)
+		d.cw.buf = append(d.cw.buf, semiColonNl...)
+	}
+}
+
+// renders a row into the buffer of d.cw, next holds the bytes after the row
+// so the text column can finish sequences that cross the end of the row
+func (d *dumper) row(b, next []byte) {
+	cw := &d.cw
+	n := len(b)
+	offset := d.start + d.offset
+	d.offset += int64(n)
+
+	// we check early on for if the dump type is "plain" (no formatting, its just a stream of bytes)
+	// and we don't have to do any hard work
+	if dumpType == dumpPlain {
+		for _, v := range b {
+			cw.buf = append(cw.buf, d.tables.hex[v][:]...)
+		}
+		return
+	}
+
+	if dumpType == dumpCformat {
+		cw.buf = append(cw.buf, doubleSpace...)
+		for i, v := range b {
+			cw.buf = append(cw.buf, d.tables.cfmt[v][:]...)
+			// no space at EOL
+			if i != n-1 {
+				cw.buf = append(cw.buf, commaSpace...)
+			} else if n == d.cols {
+				cw.buf = append(cw.buf, comma...)
+			}
+		}
+		cw.buf = append(cw.buf, newLine...)
+		return
+	}
+
+	if opts.Autoskip && isEmpty(&b) {
+		d.nulLine++
+		if d.nulLine == 2 {
+			cw.plain(asterisk)
+			cw.plain(newLine)
+		}
+		if d.nulLine > 1 {
+			// zeros never continue a glyph
+			d.text.skip(d.offset)
+			return
+		}
+	} else {
+		d.nulLine = 0
+	}
+
+	// writing the 0000000: part
+	d.hexOffset = strconv.AppendInt(d.hexOffset[:0], offset, d.colFmt)
+	if USE_COLOR {
+		cw.set(GREY, false)
+	}
+	if len(d.hexOffset) < 7 {
+		cw.buf = append(cw.buf, zeroHeader[:7-len(d.hexOffset)]...)
+	}
+	cw.buf = append(cw.buf, d.hexOffset...)
+	cw.buf = append(cw.buf, zeroHeader[7:]...)
+
+	if dumpType == dumpBinary {
+		// dump binary values
+		for i, k := 0, d.octs; i < n; i, k = i+1, k+d.octs {
+			bits := d.tables.binary[b[i]][:]
+
+			if USE_COLOR {
+				for j := range bits {
+					if bits[j] == '1' {
+						cw.write(binaryOne, false, bits[j:j+1])
+					} else {
+						cw.write(binaryZero, false, bits[j:j+1])
+					}
+				}
+			} else {
+				cw.plain(bits)
+			}
+
+			if k == d.octs*d.groupSize {
+				k = 0
+				cw.gap(space)
+			}
+		}
+	} else {
+		// hex values -- default
+		for i, k := 0, d.octs; i < n; i, k = i+1, k+d.octs {
+			hex := d.tables.hex[b[i]][:]
+
+			if USE_COLOR {
+				cw.byteColor(d.color, b[i], hex)
+			} else {
+				cw.plain(hex)
+			}
+
+			if k == d.octs*d.groupSize {
+				k = 0
+				cw.gap(space)
+			}
+		}
+	}
+
+	if n < d.cols {
+		for i := n * d.octs; i < d.cols*d.octs; i++ {
+			cw.gap(space)
+
+			if i%d.octs == 1 {
+				cw.gap(space)
+			}
+		}
+	}
+
+	cw.gap(space)
+
+	// |hello,.world!|
+	if opts.Bars {
+		if USE_COLOR {
+			cw.write(GREY, false, bar)
+		} else {
+			cw.plain(bar)
+		}
+	}
+
+	d.text.render(cw, b, next, d.color)
+
+	if opts.Bars {
+		if USE_COLOR {
+			cw.write(GREY, false, bar)
+		} else {
+			cw.plain(bar)
+		}
+	}
+
+	cw.plain(newLine)
+}
+
+// dumps the rows of b, which holds the whole input
+func (d *dumper) slice(b []byte, w io.Writer) error {
+	d.header()
+	for i := 0; i < len(b); i += d.cols {
+		if d.skipHole() {
+			i = int(d.offset) - d.cols
+			if _, err := w.Write(d.cw.flush()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		end := min(i+d.cols, len(b))
+		d.row(b[i:end], b[end:min(end+utf8.UTFMax, len(b))])
+
+		if _, err := w.Write(d.cw.flush()); err != nil {
+			return err
+		}
+	}
+
+	d.footer(int64(len(b)))
+	_, err := w.Write(d.cw.flush())
+	return err
+}
+
+// dumps r one row at a time
+func (d *dumper) stream(r io.Reader, w io.Writer) error {
+	var (
+		br   = bufio.NewReader(r)
+		line = make([]byte, d.cols)
+		size int64
+	)
+
+	d.header()
+	for {
+		n, err := io.ReadFull(br, line)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("hexxy: %v", err)
+		}
+		if n == 0 {
+			break
+		}
+
+		// multi-byte sequences may continue in the next row
+		next, _ := br.Peek(utf8.UTFMax)
+		d.markStreams(d.start + d.offset + int64(n))
+		d.row(line[:n], next)
+		size += int64(n)
+
+		if _, err := w.Write(d.cw.flush()); err != nil {
+			return err
+		}
+	}
+
+	d.footer(size)
+	_, err := w.Write(d.cw.flush())
+	return err
+}
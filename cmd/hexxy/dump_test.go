@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+var rowTypes = []struct {
+	name string
+	typ  int
+}{
+	{"hex", dumpHex},
+	{"binary", dumpBinary},
+	{"include", dumpCformat},
+	{"plain", dumpPlain},
+}
+
+// renders the row of in at i, wrapping around at the end
+func renderRow(d *dumper, in []byte, i int) {
+	start := i * d.cols % (len(in) - d.cols - utf8.UTFMax)
+	end := start + d.cols
+	d.row(in[start:end], in[end:end+utf8.UTFMax])
+	d.cw.flush()
+}
+
+func BenchmarkRow(b *testing.B) {
+	in := benchInput()
+	for _, rt := range rowTypes {
+		for _, color := range []bool{false, true} {
+			name := rt.name
+			if color {
+				name += "/color"
+			}
+
+			b.Run(name, func(b *testing.B) {
+				d := newBenchDumper(b, rt.typ, color)
+				b.SetBytes(int64(d.cols))
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					renderRow(d, in, i)
+				}
+			})
+		}
+	}
+}
+
+// once the buffers have grown a row must not allocate
+func TestRowAllocs(t *testing.T) {
+	in := benchInput()
+	for _, rt := range rowTypes {
+		for _, color := range []bool{false, true} {
+			d := newBenchDumper(t, rt.typ, color)
+
+			i := 0
+			allocs := testing.AllocsPerRun(1000, func() {
+				renderRow(d, in, i)
+				i++
+			})
+			if allocs != 0 {
+				t.Errorf("%s, color %v: %v allocations per row", rt.name, color, allocs)
+			}
+		}
+	}
+}
@@ -34,6 +34,24 @@ func (e InvalidByteError) Error() string {
 	return fmt.Sprintf("encoding/hex: invalid byte: %#U", rune(e))
 }
 
+// the rendered form of every byte value, looked up instead of encoding every byte
+type byteTables struct {
+	hex    [256][2]byte
+	binary [256][8]byte
+	cfmt   [256][4]byte
+}
+
+func newByteTables(hextable string) *byteTables {
+	t := &byteTables{}
+	for i := 0; i < 256; i++ {
+		b := []byte{byte(i)}
+		hexEncode(t.hex[i][:], b, hextable)
+		binaryEncode(t.binary[i][:], b)
+		cfmtEncode(t.cfmt[i][:], b, hextable)
+	}
+	return t
+}
+
 func binaryEncode(dst, src []byte) {
 	d := uint(0)
 	_, _ = src[0], dst[7]
@@ -79,6 +97,14 @@ func cfmtEncode(dst, src []byte, hextable string) {
 	dst[0] = '0'
 }
 
+func escapeEncode(dst, src []byte, hextable string) {
+	b := src[0]
+	dst[3] = hextable[b&0x0f]
+	dst[2] = hextable[b>>4]
+	dst[1] = 'x'
+	dst[0] = '\\'
+}
+
 // copied from encoding/hex package in order to add support for uppercase hex
 func hexEncode(dst, src []byte, hextable string) {
 	b := src[0]
@@ -133,6 +159,32 @@ func fromHexChar(c byte) (byte, bool) {
 	return 0, false
 }
 
+// byte categories in the style of hexyl
+const (
+	catNull = iota
+	catPrintable
+	catWhitespace
+	catOther
+	catNonASCII
+)
+
+var categoryNames = [...]string{"null", "printable", "whitespace", "other", "non-ascii"}
+
+func byteCategory(b byte) int {
+	switch {
+	case b == 0:
+		return catNull
+	case b > 0x20 && b < 0x7f:
+		return catPrintable
+	case b == ' ', b == '\t', b == '\n', b == '\f', b == '\r':
+		return catWhitespace
+	case b < 0x80:
+		return catOther
+	default:
+		return catNonASCII
+	}
+}
+
 // check if entire line is full of isEmpty []byte{0} bytes (nul in C)
 func isEmpty(b *[]byte) bool {
 	for i := 0; i < len(*b); i++ {
@@ -143,14 +195,29 @@ func isEmpty(b *[]byte) bool {
 	return true
 }
 
-// check if filename character contains problematic characters
+// check if filename character can't be used in an identifier
 func isSpecial(b byte) bool {
-	switch b {
-	case '/', '!', '#', '$', '%', '^', '&', '*', '(', ')', ';', ':', '|', '{', '}', '\\', '~', '`':
-		return true
-	default:
+	switch {
+	case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9', b == '_':
 		return false
+	default:
+		return true
+	}
+}
+
+// turns a filename into a variable name by replacing problematic characters with '_'
+// a leading digit is prefixed with '_' so the result is valid in C, Go, Python and JS
+func identifier(filename string) string {
+	name := []byte(filename)
+	for i := 0; i < len(name); i++ {
+		if isSpecial(name[i]) {
+			name[i] = '_'
+		}
+	}
+	if len(name) > 0 && name[0] >= '0' && name[0] <= '9' {
+		name = append([]byte{'_'}, name...)
 	}
+	return string(name)
 }
 
 // quick binary tree check
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// how a string literal is laid out for each language
+type escapeStyle struct {
+	header  string // written once before the first line, %s is the variable name
+	prefix  string // written before every line, %s is the variable name
+	joiner  string // written between lines
+	trailer string // written after the last line
+	empty   bool   // write an empty literal when there is no input
+}
+
+var escapeStyles = map[string]escapeStyle{
+	"c":      {header: "unsigned char %s[] =\n", prefix: "\"", joiner: "\n", trailer: ";\n", empty: true},
+	"python": {header: "%s = b\"\"\n", prefix: "%s += b\"", joiner: "\n", trailer: "\n"},
+	"go":     {header: "var %s = []byte(\n", prefix: "\t\"", joiner: " +\n", trailer: ",\n)\n", empty: true},
+	"js":     {header: "const %s =\n", prefix: "  \"", joiner: " +\n", trailer: ";\n", empty: true},
+}
+
+// a forbidden byte found in the input
+type badChar struct {
+	offset int64
+	value  byte
+}
+
+// parses a list of bytes like "00,0a,0d", "\x00\x0a" or "0x00 0x0a"
+func parseBadChars(s string) (*[256]bool, error) {
+	bad := new([256]bool)
+
+	s = strings.ReplaceAll(s, `\x`, ",")
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+
+	for _, f := range fields {
+		f = strings.TrimPrefix(strings.TrimPrefix(f, "0x"), "0X")
+		if len(f) != 2 {
+			return nil, fmt.Errorf("bad-chars: %q is not a hex byte", f)
+		}
+
+		var b [1]byte
+		if _, err := hexDecode(b[:], []byte(f)); err != nil {
+			return nil, fmt.Errorf("bad-chars: %v", err)
+		}
+		bad[b[0]] = true
+	}
+
+	return bad, nil
+}
+
+// EscapeDump writes r as escaped string literals ("\x41\x42") for the language given with --escape
+// bytes listed in --bad-chars are highlighted and their offsets are reported on stderr
+func EscapeDump(r io.Reader, w io.Writer, filename string) error {
+	style, ok := escapeStyles[opts.Escape]
+	if !ok {
+		return fmt.Errorf("unknown escape style %q", opts.Escape)
+	}
+
+	bad := new([256]bool)
+	if opts.BadChars != "" {
+		var err error
+		if bad, err = parseBadChars(opts.BadChars); err != nil {
+			return err
+		}
+	}
+
+	cols := 16
+	if opts.Columns != -1 {
+		cols = opts.Columns
+	}
+	if cols < 1 {
+		return fmt.Errorf("escape: line length must be at least 1, got %d", cols)
+	}
+
+	caps := ldigits
+	if opts.Upper {
+		caps = udigits
+	}
+
+	var (
+		name   = identifier(filename)
+		line   = make([]byte, cols)
+		char   = make([]byte, 4)
+		offset int64
+		rows   int
+		found  []badChar
+		err    error
+		n      int
+	)
+
+	if opts.Seek != -1 {
+		offset = opts.Seek
+	}
+
+	fmt.Fprintf(w, style.header, name)
+
+	r = bufio.NewReader(r)
+	for {
+		n, err = io.ReadFull(r, line)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+
+		if rows > 0 {
+			io.WriteString(w, style.joiner)
+		}
+		writeEscapePrefix(w, style.prefix, name)
+
+		for i := 0; i < n; i++ {
+			escapeEncode(char, line[i:i+1], caps)
+
+			if bad[line[i]] {
+				found = append(found, badChar{offset: offset + int64(i), value: line[i]})
+				if USE_COLOR {
+					w.Write(RED)
+					w.Write(char)
+					w.Write(CLEAR)
+					continue
+				}
+			}
+			w.Write(char)
+		}
+
+		w.Write([]byte("\""))
+		offset += int64(n)
+		rows++
+	}
+
+	if rows == 0 && style.empty {
+		writeEscapePrefix(w, style.prefix, name)
+		w.Write([]byte("\""))
+	}
+
+	io.WriteString(w, style.trailer)
+
+	for _, b := range found {
+		fmt.Fprintf(os.Stderr, "bad character 0x%02x at offset %#x\n", b.value, b.offset)
+	}
+	if len(found) > 0 {
+		fmt.Fprintf(os.Stderr, "found %d bad characters\n", len(found))
+	}
+
+	return nil
+}
+
+func writeEscapePrefix(w io.Writer, prefix, name string) {
+	if strings.Contains(prefix, "%s") {
+		fmt.Fprintf(w, prefix, name)
+		return
+	}
+	io.WriteString(w, prefix)
+}
+
+// EscapeReverse reads string literals and turns their escapes back into bytes
+// \xNN, octal (\0, \101) and the usual single character escapes are understood,
+// everything outside of quotes is ignored unless the input contains no quotes at all
+func EscapeReverse(r io.Reader, w io.Writer) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(w)
+	defer out.Flush()
+
+	// bare escapes like \x41\x42 pasted from somewhere else
+	if !bytes.ContainsAny(data, "\"'") {
+		_, err := unescape(out, bytes.TrimSpace(data), 0)
+		return err
+	}
+
+	for i := 0; i < len(data); i++ {
+		q := data[i]
+		if q != '"' && q != '\'' {
+			continue
+		}
+
+		n, err := unescape(out, data[i+1:], q)
+		if err != nil {
+			return err
+		}
+		i += n + 1
+	}
+
+	return nil
+}
+
+// decodes a literal until the closing quote, returns the number of bytes consumed
+// not counting the quote itself. a quote of 0 decodes everything
+func unescape(w *bufio.Writer, src []byte, quote byte) (int, error) {
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		if quote != 0 && c == quote {
+			return i, nil
+		}
+		if c != '\\' {
+			w.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i >= len(src) {
+			return i, fmt.Errorf("escape: trailing backslash")
+		}
+
+		switch c = src[i]; c {
+		case 'x', 'X':
+			if i+2 >= len(src) {
+				return i, fmt.Errorf("escape: short \\x escape")
+			}
+			var b [1]byte
+			if _, err := hexDecode(b[:], src[i+1:i+3]); err != nil {
+				return i, fmt.Errorf("escape: %v", err)
+			}
+			w.WriteByte(b[0])
+			i += 2
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			v := 0
+			j := i
+			for ; j < len(src) && j < i+3 && src[j] >= '0' && src[j] <= '7'; j++ {
+				v = v*8 + int(src[j]-'0')
+			}
+			if v > 0xff {
+				return i, fmt.Errorf("escape: octal escape \\%s out of range", src[i:j])
+			}
+			w.WriteByte(byte(v))
+			i = j - 1
+		case 'n':
+			w.WriteByte('\n')
+		case 't':
+			w.WriteByte('\t')
+		case 'r':
+			w.WriteByte('\r')
+		case 'a':
+			w.WriteByte('\a')
+		case 'b':
+			w.WriteByte('\b')
+		case 'f':
+			w.WriteByte('\f')
+		case 'v':
+			w.WriteByte('\v')
+		case 'e':
+			w.WriteByte(0x1b)
+		case '\n':
+			// line continuation
+		default:
+			// \\ \" \' and anything unknown stand for themselves
+			w.WriteByte(c)
+		}
+	}
+
+	if quote != 0 {
+		return len(src), fmt.Errorf("escape: unterminated string literal")
+	}
+	return len(src), nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEscapeRoundTrip(t *testing.T) {
+	in := roundTripInput()
+	for _, style := range []string{"c", "python", "go", "js"} {
+		for _, args := range [][]string{nil, {"-c", "1"}, {"-c", "7", "-u"}} {
+			t.Run(style+"/"+strings.Join(args, " "), func(t *testing.T) {
+				setFlags(t, append([]string{"--escape", style}, args...)...)
+
+				for _, data := range [][]byte{in[:5000], in[:1], nil} {
+					var text, out bytes.Buffer
+					if err := EscapeDump(bytes.NewReader(data), &text, "1 in.bin"); err != nil {
+						t.Fatal(err)
+					}
+					if err := EscapeReverse(&text, &out); err != nil {
+						t.Fatalf("%d bytes: %v", len(data), err)
+					}
+					if !bytes.Equal(out.Bytes(), data) {
+						t.Errorf("%d bytes: the output differs at byte %d", len(data), firstDiff(out.Bytes(), data))
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestEscapeReverse(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string // the output, or the error
+	}{
+		{"bare", `\x41\X42C`, "ABC"},
+		{"outside quotes", "x = b\"\\x41\" # \\x42\ny += 'B'\n", "AB"},
+		{"octal", `"\0\101\1012\08"`, "\x00AA2\x008"},
+		{"single characters", `"\n\t\r\a\b\f\v\e\\\"\'\q"`, "\n\t\r\a\b\f\v\x1b\\\"'q"},
+		{"continuation", "\"a\\\nb\"", "ab"},
+		{"quotes in quotes", `"'" '"'`, `'"`},
+		{"unterminated", `"\x41`, "escape: unterminated string literal"},
+		{"trailing backslash", `\x41\`, "escape: trailing backslash"},
+		{"short", `\x4`, "escape: short \\x escape"},
+		{"not hex", `"\x4g"`, "escape: encoding/hex: invalid byte: U+0067 'g'"},
+		{"octal range", `"\777"`, "escape: octal escape \\777 out of range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := EscapeReverse(strings.NewReader(tt.in), &out)
+			switch {
+			case err != nil && err.Error() != tt.want:
+				t.Errorf("%v, want %q", err, tt.want)
+			case err == nil && out.String() != tt.want:
+				t.Errorf("%q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,588 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	mifContent = regexp.MustCompile(`(?i)\bCONTENT\s+BEGIN\b`)
+	vhdlRange  = regexp.MustCompile(`(?i)\barray\s*\(\s*(\d+)\s+to\b`)
+)
+
+// returns the word width in bytes and whether words are stored little endian
+func hdlWord() (int, bool, error) {
+	width := 1
+	if opts.WordWidth != -1 {
+		width = opts.WordWidth
+	}
+	if width < 1 || width > 64 {
+		return 0, false, fmt.Errorf("hdl: word width must be between 1 and 64 bytes, got %d", width)
+	}
+	return width, opts.Endian == "little", nil
+}
+
+// appends one word in hex, the most significant byte first
+func appendWord(dst []byte, word []byte, little bool, hextable string) []byte {
+	for i := range word {
+		b := word[i]
+		if little {
+			b = word[len(word)-1-i]
+		}
+		dst = append(dst, hextable[b>>4], hextable[b&0x0f])
+	}
+	return dst
+}
+
+// turns a word value back into bytes in memory order
+func wordBytes(tok string, radix, width int, little bool) ([]byte, error) {
+	tok = strings.ReplaceAll(tok, "_", "")
+
+	v, ok := new(big.Int).SetString(tok, radix)
+	if !ok || v.Sign() < 0 {
+		return nil, fmt.Errorf("invalid word %q", tok)
+	}
+	if v.BitLen() > width*8 {
+		return nil, fmt.Errorf("word %q does not fit in %d bytes", tok, width)
+	}
+
+	word := v.FillBytes(make([]byte, width))
+	if little {
+		for i, j := 0, len(word)-1; i < j; i, j = i+1, j-1 {
+			word[i], word[j] = word[j], word[i]
+		}
+	}
+	return word, nil
+}
+
+// VHDL names start with a letter and have no leading, trailing or double underscores
+func vhdlIdentifier(filename string) string {
+	var name []byte
+	for _, c := range []byte(path.Base(filename)) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			name = append(name, c)
+		case len(name) > 0 && name[len(name)-1] != '_':
+			name = append(name, '_')
+		}
+	}
+	name = bytes.TrimRight(name, "_")
+
+	switch {
+	case len(name) == 0:
+		return "mem"
+	case name[0] <= '9':
+		return "mem_" + string(name)
+	}
+	return string(name)
+}
+
+// HDLDump writes r as a memory initialisation file for the format given with --hdl
+// words are --word-width bytes wide and --columns words are written per line
+func HDLDump(r io.Reader, w io.Writer, filename string) error {
+	width, little, err := hdlWord()
+	if err != nil {
+		return err
+	}
+
+	base, _, err := baseAddress()
+	if err != nil {
+		return err
+	}
+	if base%uint32(width) != 0 {
+		return fmt.Errorf("hdl: base address %#x is not aligned to the word width", base)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	// the last word is padded with zeros
+	if rem := len(data) % width; rem != 0 {
+		data = append(data, make([]byte, width-rem)...)
+	}
+
+	cols := 1
+	if opts.Columns != -1 {
+		cols = opts.Columns
+	}
+	if cols < 1 {
+		return fmt.Errorf("hdl: words per line must be at least 1, got %d", cols)
+	}
+
+	caps := ldigits
+	if opts.Upper {
+		caps = udigits
+	}
+
+	var (
+		name  = identifier(filename)
+		words = len(data) / width
+		start = int(base) / width
+		buf   []byte
+	)
+
+	switch opts.HDL {
+	case "verilog":
+		fmt.Fprintf(w, "// %s: %d words of %d bits\n", name, words, width*8)
+
+		// the image starts at the first address and ends at the last one,
+		// so the zeros skipped at either end come back on reverse
+		fmt.Fprintf(w, "@%x\n", start)
+		skipped := false
+		for i := 0; i < words; i += cols {
+			row := data[i*width : min(i+cols, words)*width]
+			if opts.Autoskip && isEmpty(&row) {
+				skipped = true
+				continue
+			}
+
+			// $readmemh addresses are in words
+			if skipped {
+				fmt.Fprintf(w, "@%x\n", start+i)
+				skipped = false
+			}
+
+			buf = buf[:0]
+			for j := 0; j < len(row); j += width {
+				if j > 0 {
+					buf = append(buf, ' ')
+				}
+				buf = appendWord(buf, row[j:j+width], little, caps)
+			}
+			buf = append(buf, '\n')
+			w.Write(buf)
+		}
+		if skipped {
+			fmt.Fprintf(w, "@%x\n", start+words)
+		}
+	case "vhdl":
+		name = vhdlIdentifier(filename)
+		fmt.Fprintf(w, "library ieee;\nuse ieee.std_logic_1164.all;\n\npackage %s_pkg is\n", name)
+		fmt.Fprintf(w, "  type %s_t is array (%d to %d) of std_logic_vector(%d downto 0);\n", name, start, start+max(words, 1)-1, width*8-1)
+		fmt.Fprintf(w, "  constant %s : %s_t := (\n", name, name)
+
+		for i := 0; i < words; i += cols {
+			buf = append(buf[:0], "    "...)
+			if words == 1 {
+				// (x"41") would be a parenthesised expression, an aggregate of one needs a name
+				buf = strconv.AppendInt(buf, int64(start), 10)
+				buf = append(buf, " => "...)
+			}
+			for j := i; j < min(i+cols, words); j++ {
+				buf = append(buf, 'x', '"')
+				buf = appendWord(buf, data[j*width:(j+1)*width], little, caps)
+				buf = append(buf, '"')
+				if j != words-1 {
+					buf = append(buf, ',')
+					if j != min(i+cols, words)-1 {
+						buf = append(buf, ' ')
+					}
+				}
+			}
+			buf = append(buf, '\n')
+			w.Write(buf)
+		}
+		if words == 0 {
+			fmt.Fprintf(w, "    others => (others => '0')\n")
+		}
+
+		fmt.Fprintf(w, "  );\nend package;\n")
+	case "mif":
+		// a memory has at least one word, the zeros after the content are restored from DEPTH
+		if words == 0 {
+			data, words = make([]byte, width), 1
+		}
+		fmt.Fprintf(w, "-- %s\nDEPTH = %d;\nWIDTH = %d;\nADDRESS_RADIX = HEX;\nDATA_RADIX = HEX;\nCONTENT\nBEGIN\n", name, start+words, width*8)
+
+		for i := 0; i < words; i += cols {
+			row := data[i*width : min(i+cols, words)*width]
+			// the first row marks where the image starts
+			if opts.Autoskip && i > 0 && isEmpty(&row) {
+				continue
+			}
+
+			buf = strconv.AppendInt(buf[:0], int64(start+i), 16)
+			buf = append(buf, " :"...)
+			for j := 0; j < len(row); j += width {
+				buf = append(buf, ' ')
+				buf = appendWord(buf, row[j:j+width], little, caps)
+			}
+			buf = append(buf, ";\n"...)
+			w.Write(buf)
+		}
+
+		fmt.Fprintf(w, "END;\n")
+	case "coe":
+		fmt.Fprintf(w, "; %s: %d words of %d bits\nmemory_initialization_radix=16;\nmemory_initialization_vector=\n", name, words, width*8)
+
+		for i := 0; i < words; i += cols {
+			buf = buf[:0]
+			for j := i; j < min(i+cols, words); j++ {
+				buf = appendWord(buf, data[j*width:(j+1)*width], little, caps)
+				if j == words-1 {
+					buf = append(buf, ';')
+				} else {
+					buf = append(buf, ',')
+					if j != min(i+cols, words)-1 {
+						buf = append(buf, ' ')
+					}
+				}
+			}
+			buf = append(buf, '\n')
+			w.Write(buf)
+		}
+		if words == 0 {
+			fmt.Fprintf(w, "0;\n")
+		}
+	default:
+		return fmt.Errorf("unknown hdl format %q", opts.HDL)
+	}
+
+	return nil
+}
+
+// HDLReverse extracts the memory contents from a file in the format given with --hdl
+// addresses found in the file are honoured and gaps are filled with zeros like an
+// uninitialised memory
+func HDLReverse(r io.Reader, w io.Writer) error {
+	width, little, err := hdlWord()
+	if err != nil {
+		return err
+	}
+
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var segs []segment
+	switch opts.HDL {
+	case "verilog":
+		segs, err = readVerilog(src, width, little)
+	case "vhdl":
+		segs, err = readVHDL(src, width, little)
+	case "mif":
+		segs, err = readMIF(src, width, little)
+	case "coe":
+		segs, err = readCOE(src, width, little)
+	default:
+		return fmt.Errorf("unknown hdl format %q", opts.HDL)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", opts.HDL, err)
+	}
+
+	return writeImage(w, segs, "0")
+}
+
+// removes // and /* */ comments, as well as -- comments when dashes is set
+func stripComments(src []byte, dashes bool) []byte {
+	var out []byte
+	for i := 0; i < len(src); i++ {
+		switch {
+		case bytes.HasPrefix(src[i:], []byte("//")), dashes && bytes.HasPrefix(src[i:], []byte("--")):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			out = append(out, '\n')
+		case bytes.HasPrefix(src[i:], []byte("/*")):
+			end := bytes.Index(src[i+2:], []byte("*/"))
+			if end == -1 {
+				return out
+			}
+			i += end + 3
+			out = append(out, ' ')
+		default:
+			out = append(out, src[i])
+		}
+	}
+	return out
+}
+
+// $readmemh files are whitespace separated words with optional @address markers
+func readVerilog(src []byte, width int, little bool) ([]segment, error) {
+	var (
+		segs []segment
+		addr uint64
+	)
+
+	for _, tok := range strings.Fields(string(stripComments(src, false))) {
+		if tok[0] == '@' {
+			a, err := strconv.ParseUint(tok[1:], 16, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q", tok)
+			}
+			addr = a * uint64(width)
+			// an address without words after it still belongs to the image
+			segs = append(segs, segment{addr: uint32(addr)})
+			continue
+		}
+
+		// unknown and high impedance bits are read as zero
+		tok = strings.Map(func(r rune) rune {
+			if r == 'x' || r == 'X' || r == 'z' || r == 'Z' {
+				return '0'
+			}
+			return r
+		}, tok)
+
+		word, err := wordBytes(tok, 16, width, little)
+		if err != nil {
+			return nil, err
+		}
+		segs = append(segs, segment{addr: uint32(addr), data: word})
+		addr += uint64(width)
+	}
+
+	return segs, nil
+}
+
+// picks up every x"..." (hex) or "..." (binary) literal after the first :=
+// the words start at the first index of the array type
+func readVHDL(src []byte, width int, little bool) ([]segment, error) {
+	var (
+		segs []segment
+		addr uint64
+	)
+
+	src = stripComments(src, true)
+	if m := vhdlRange.FindSubmatch(src); m != nil {
+		first, err := strconv.ParseUint(string(m[1]), 10, 32)
+		if err != nil || first*uint64(width) >= 1<<32 {
+			return nil, fmt.Errorf("invalid array index %s", m[1])
+		}
+		addr = first * uint64(width)
+	}
+	if i := bytes.Index(src, []byte(":=")); i != -1 {
+		src = src[i+2:]
+	}
+
+	for i := 0; i < len(src); i++ {
+		if src[i] != '"' {
+			continue
+		}
+
+		end := bytes.IndexByte(src[i+1:], '"')
+		if end == -1 {
+			return nil, fmt.Errorf("unterminated string literal")
+		}
+		lit := string(src[i+1 : i+1+end])
+
+		radix := 2
+		if i > 0 && (src[i-1] == 'x' || src[i-1] == 'X') {
+			radix = 16
+		}
+		i += end + 1
+
+		word, err := wordBytes(lit, radix, width, little)
+		if err != nil {
+			return nil, err
+		}
+		segs = append(segs, segment{addr: uint32(addr), data: word})
+		addr += uint64(width)
+	}
+
+	return segs, nil
+}
+
+func mifRadix(s string) (int, error) {
+	switch strings.ToUpper(s) {
+	case "HEX":
+		return 16, nil
+	case "DEC", "UNS":
+		return 10, nil
+	case "OCT":
+		return 8, nil
+	case "BIN":
+		return 2, nil
+	}
+	return 0, fmt.Errorf("unsupported radix %q", s)
+}
+
+// Altera memory initialisation files, WIDTH and the radix settings in the header
+// take precedence over --word-width
+func readMIF(src []byte, width int, little bool) ([]segment, error) {
+	src = stripComments(src, true)
+
+	loc := mifContent.FindIndex(src)
+	if loc == nil {
+		return nil, fmt.Errorf("missing CONTENT BEGIN")
+	}
+
+	var (
+		segs      []segment
+		addrRadix = 16
+		dataRadix = 16
+		depth     uint64
+		err       error
+	)
+
+	for _, stmt := range strings.Split(string(src[:loc[0]]), ";") {
+		key, val, _ := strings.Cut(stmt, "=")
+		key, val = strings.ToUpper(strings.TrimSpace(key)), strings.TrimSpace(val)
+
+		switch key {
+		case "WIDTH":
+			bits, perr := strconv.Atoi(val)
+			if perr != nil || bits%8 != 0 || bits == 0 {
+				return nil, fmt.Errorf("WIDTH %q is not a whole number of bytes", val)
+			}
+			width = bits / 8
+		case "DEPTH":
+			depth, err = strconv.ParseUint(val, 10, 33)
+			if err != nil || depth == 0 {
+				return nil, fmt.Errorf("invalid DEPTH %q", val)
+			}
+		case "ADDRESS_RADIX":
+			addrRadix, err = mifRadix(val)
+		case "DATA_RADIX":
+			dataRadix, err = mifRadix(val)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if depth == 0 {
+		return nil, fmt.Errorf("missing DEPTH")
+	}
+
+	for _, stmt := range strings.Split(string(src[loc[1]:]), ";") {
+		stmt = strings.TrimSpace(stmt)
+		if stmt == "" {
+			continue
+		}
+		if strings.EqualFold(stmt, "END") {
+			break
+		}
+
+		addrs, vals, ok := strings.Cut(stmt, ":")
+		if !ok {
+			return nil, fmt.Errorf("expected 'address : data' but found %q", stmt)
+		}
+
+		// either a single address or a range like [0..ff]
+		addrs = strings.TrimSpace(addrs)
+		first, last := addrs, addrs
+		if strings.HasPrefix(addrs, "[") {
+			first, last, ok = strings.Cut(strings.Trim(addrs, "[]"), "..")
+			if !ok {
+				return nil, fmt.Errorf("invalid address range %q", addrs)
+			}
+		}
+
+		lo, err := strconv.ParseUint(strings.TrimSpace(first), addrRadix, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q", first)
+		}
+		hi, err := strconv.ParseUint(strings.TrimSpace(last), addrRadix, 32)
+		if err != nil || hi < lo {
+			return nil, fmt.Errorf("invalid address %q", last)
+		}
+
+		var words [][]byte
+		for _, tok := range strings.Fields(vals) {
+			word, err := wordBytes(tok, dataRadix, width, little)
+			if err != nil {
+				return nil, err
+			}
+			words = append(words, word)
+		}
+		if len(words) == 0 {
+			return nil, fmt.Errorf("no data for address %q", addrs)
+		}
+
+		// a range ends at the last word of the memory
+		if lo >= depth {
+			return nil, fmt.Errorf("address %q is beyond DEPTH %d", first, depth)
+		}
+		hi = min(hi, depth-1)
+
+		// a range repeats its data, a single address may be followed by several words
+		count := max(hi-lo+1, uint64(len(words)))
+		if (lo+count)*uint64(width) > 1<<32 {
+			return nil, fmt.Errorf("address %q is beyond 4 GiB", addrs)
+		}
+
+		var data []byte
+		if len(words) == 1 {
+			data = bytes.Repeat(words[0], int(count))
+		} else {
+			data = make([]byte, 0, int(count)*width)
+			for i := 0; i < int(count); i++ {
+				data = append(data, words[i%len(words)]...)
+			}
+		}
+		segs = append(segs, segment{addr: uint32(lo * uint64(width)), data: data})
+	}
+
+	// the words after the content are zeros up to DEPTH
+	end := depth * uint64(width)
+	if end >= 1<<32 {
+		return nil, fmt.Errorf("DEPTH %d is beyond 4 GiB", depth)
+	}
+	return append(segs, segment{addr: uint32(end)}), nil
+}
+
+// Xilinx coefficient files, only the radix and vector keys are used
+// they have no addresses, so the words start at --base-addr
+func readCOE(src []byte, width int, little bool) ([]segment, error) {
+	base, _, err := baseAddress()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		segs  []segment
+		radix = 10
+		addr  = uint64(base)
+	)
+
+	// comments are lines starting with ';'
+	var text strings.Builder
+	sc := bufio.NewScanner(bytes.NewReader(src))
+	for sc.Scan() {
+		if line := sc.Text(); !strings.HasPrefix(strings.TrimSpace(line), ";") {
+			text.WriteString(line)
+			text.WriteByte('\n')
+		}
+	}
+
+	for _, stmt := range strings.Split(text.String(), ";") {
+		key, val, ok := strings.Cut(stmt, "=")
+		if !ok {
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "memory_initialization_radix", "radix":
+			r, err := strconv.Atoi(strings.TrimSpace(val))
+			if err != nil {
+				return nil, fmt.Errorf("invalid radix %q", val)
+			}
+			radix = r
+		case "memory_initialization_vector", "coefdata":
+			fields := strings.FieldsFunc(val, func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+			})
+			for _, tok := range fields {
+				word, err := wordBytes(tok, radix, width, little)
+				if err != nil {
+					return nil, err
+				}
+				segs = append(segs, segment{addr: uint32(addr), data: word})
+				addr += uint64(width)
+			}
+		}
+	}
+
+	return segs, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHDLRoundTrip(t *testing.T) {
+	// zeros at both ends and in the middle, which -a leaves out of the file
+	in := append(make([]byte, 64), roundTripInput()[:4000]...)
+	in = append(in, make([]byte, 64)...)
+
+	for _, format := range []string{"verilog", "vhdl", "mif", "coe"} {
+		for _, args := range [][]string{
+			nil,
+			{"-a"},
+			{"-a", "-c", "2", "--word-width", "2"},
+			{"-c", "3", "--word-width", "4", "--endian", "little"},
+			{"-a", "-c", "5", "--base-addr", "0x100"},
+		} {
+			t.Run(format+"/"+strings.Join(args, " "), func(t *testing.T) {
+				setFlags(t, append([]string{"--hdl", format}, args...)...)
+
+				for _, data := range [][]byte{in, in[:64], in[64:68]} {
+					var text, out bytes.Buffer
+					if err := HDLDump(bytes.NewReader(data), &text, "1 in.bin"); err != nil {
+						t.Fatal(err)
+					}
+					if err := HDLReverse(&text, &out); err != nil {
+						t.Fatalf("%d bytes: %v", len(data), err)
+					}
+					if !bytes.Equal(out.Bytes(), data) {
+						t.Errorf("%d bytes: the image differs at byte %d of %d", len(data), firstDiff(out.Bytes(), data), out.Len())
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestHDLReverse(t *testing.T) {
+	tests := []struct {
+		format string
+		args   []string
+		in     string
+		want   string // the image, or the error
+	}{
+		{"verilog", nil, "// comment\n41 @3 42 /* 43 */ 44\n", "A\x00\x00BD"},
+		{"verilog", nil, "@2 41\n", "A"},
+		{"verilog", nil, "41 @4\n", "A\x00\x00\x00"},
+		{"verilog", nil, "4x zz\n", "\x40\x00"},
+		{"verilog", []string{"--word-width", "2", "--endian", "little"}, "4142 @2 43\n", "BA\x00\x00C\x00"},
+		{"verilog", nil, "@g 41\n", `verilog: invalid address "@g"`},
+		{"verilog", nil, "141\n", `verilog: word "141" does not fit in 1 bytes`},
+		{"vhdl", nil, "constant m : t := (x\"41\", \"01000010\", -- x\"43\"\n 2 => x\"44\");", "ABD"},
+		{"vhdl", []string{"--base-addr", "0"}, "type t is array (2 to 3) of std_logic_vector(7 downto 0);\nconstant m : t := (x\"41\", x\"42\");", "\x00\x00AB"},
+		{"vhdl", nil, "constant m : t := (x\"41);", "vhdl: unterminated string literal"},
+		{"vhdl", nil, "constant m : t := (x\"4g\");", `vhdl: invalid word "4g"`},
+		{"mif", nil, "DEPTH = 6; WIDTH = 8;\nCONTENT BEGIN\n0 : 41;\n[2..3] : 42;\n4 : 43 44;\nEND;", "A\x00BBCD"},
+		{"mif", nil, "DEPTH = 4; WIDTH = 16; DATA_RADIX = BIN;\nCONTENT BEGIN\n[0..ff] : 100000101000010;\nEND;", "ABABABAB"},
+		{"mif", nil, "DEPTH = 4; WIDTH = 8;\nCONTENT BEGIN\n1 : 41;\nEND;", "A\x00\x00"},
+		{"mif", nil, "DEPTH = 4; WIDTH = 8;\n0 : 41;", "mif: missing CONTENT BEGIN"},
+		{"mif", nil, "WIDTH = 8;\nCONTENT BEGIN\n0 : 41;\nEND;", "mif: missing DEPTH"},
+		{"mif", nil, "DEPTH = 0; WIDTH = 8;\nCONTENT BEGIN\nEND;", `mif: invalid DEPTH "0"`},
+		{"mif", nil, "DEPTH = 4; WIDTH = 12;\nCONTENT BEGIN\nEND;", `mif: WIDTH "12" is not a whole number of bytes`},
+		{"mif", nil, "DEPTH = 4; WIDTH = 8;\nCONTENT BEGIN\n4 : 41;\nEND;", `mif: address "4" is beyond DEPTH 4`},
+		{"mif", nil, "DEPTH = 4; WIDTH = 8;\nCONTENT BEGIN\n[0.3] : 41;\nEND;", `mif: invalid address range "[0.3]"`},
+		{"mif", nil, "DEPTH = 4; WIDTH = 8;\nCONTENT BEGIN\n0 41;\nEND;", `mif: expected 'address : data' but found "0 41"`},
+		{"mif", nil, "DEPTH = 4; WIDTH = 8;\nCONTENT BEGIN\n0 : ;\nEND;", `mif: no data for address "0"`},
+		{"mif", nil, "DEPTH = 4; WIDTH = 8; DATA_RADIX = ROT13;\nCONTENT BEGIN\nEND;", `mif: unsupported radix "ROT13"`},
+		{"coe", nil, "; comment\nmemory_initialization_radix=16;\nmemory_initialization_vector=\n41, 42,\n43;\n", "ABC"},
+		{"coe", nil, "radix=2;\ncoefdata=1000001 1000010;\n", "AB"},
+		{"coe", []string{"--base-addr", "1"}, "radix=16;\ncoefdata=41;\n", "A"},
+		{"coe", nil, "radix=x;\ncoefdata=41;\n", `coe: invalid radix "x"`},
+		{"coe", nil, "radix=16;\ncoefdata=41 4g;\n", `coe: invalid word "4g"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			setFlags(t, append([]string{"--hdl", tt.format}, tt.args...)...)
+
+			var out bytes.Buffer
+			err := HDLReverse(strings.NewReader(tt.in), &out)
+			switch {
+			case err != nil && err.Error() != tt.want:
+				t.Errorf("%q: %v, want %q", tt.in, err, tt.want)
+			case err == nil && out.String() != tt.want:
+				t.Errorf("%q: %q, want %q", tt.in, out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,520 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// the kinds of conversions understood by hexdump -e
+const (
+	hdInt       = iota // %d %i %o %u %x %X
+	hdFloat            // %e %E %f %g %G
+	hdChar             // %c
+	hdString           // %s
+	hdOffset           // %_a[dox]
+	hdEndOffset        // %_A[dox]
+	hdEscChar          // %_c
+	hdPrint            // %_p
+	hdCtrl             // %_u
+)
+
+// a single conversion and the literal text that follows it
+type hdConv struct {
+	kind   int
+	spec   string // printf style prefix like "%08.8" without the verb
+	verb   byte   // verb used with spec
+	signed bool
+	width  int
+	size   int // bytes consumed, 0 for offsets
+	text   string
+}
+
+// a format unit: [iterations]/[byte count] "format"
+type hdUnit struct {
+	iter  int
+	size  int // bytes consumed by one iteration
+	lead  string
+	convs []hdConv
+	final bool // uses %_A, so it is only written after all input
+}
+
+// names written by %_u for control characters
+var hdControlNames = [...]string{
+	"nul", "soh", "stx", "etx", "eot", "enq", "ack", "bel",
+	"bs", "ht", "lf", "vt", "ff", "cr", "so", "si",
+	"dle", "dc1", "dc2", "dc3", "dc4", "nak", "syn", "etb",
+	"can", "em", "sub", "esc", "fs", "gs", "rs", "us",
+}
+
+// expands the backslash escapes allowed in hexdump format strings
+func hdUnescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// parses one -e style format string into its format units
+func parseHexdumpFormat(s string) ([]hdUnit, error) {
+	var units []hdUnit
+
+	for i := 0; ; {
+		for i < len(s) && strings.IndexByte(" \t\r\n", s[i]) != -1 {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+
+		unit := hdUnit{iter: 1}
+		count := -1
+
+		// iteration count
+		j := i
+		for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			j++
+		}
+		if j > i {
+			unit.iter, _ = strconv.Atoi(s[i:j])
+			i = j
+		}
+
+		// byte count
+		if i < len(s) && s[i] == '/' {
+			i++
+			j = i
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("hexdump-format: missing byte count after '/'")
+			}
+			count, _ = strconv.Atoi(s[i:j])
+			i = j
+		}
+
+		for i < len(s) && strings.IndexByte(" \t\r\n", s[i]) != -1 {
+			i++
+		}
+		if i >= len(s) || s[i] != '"' {
+			return nil, fmt.Errorf("hexdump-format: expected a quoted format at %q", s[i:])
+		}
+
+		end := i + 1
+		for end < len(s) && s[end] != '"' {
+			if s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(s) {
+			return nil, fmt.Errorf("hexdump-format: unterminated format %q", s[i:])
+		}
+
+		if err := unit.parse(s[i+1:end], count); err != nil {
+			return nil, err
+		}
+		units = append(units, unit)
+		i = end + 1
+	}
+
+	return units, nil
+}
+
+// splits the text of a format unit into conversions
+func (u *hdUnit) parse(f string, count int) error {
+	var (
+		text strings.Builder
+		data int // conversions that consume input
+	)
+
+	flushText := func() {
+		if len(u.convs) == 0 {
+			u.lead = hdUnescape(text.String())
+		} else {
+			u.convs[len(u.convs)-1].text = hdUnescape(text.String())
+		}
+		text.Reset()
+	}
+
+	for i := 0; i < len(f); i++ {
+		if f[i] != '%' {
+			text.WriteByte(f[i])
+			continue
+		}
+		if i+1 < len(f) && f[i+1] == '%' {
+			text.WriteByte('%')
+			i++
+			continue
+		}
+		flushText()
+
+		// flags, width and precision
+		j := i + 1
+		for j < len(f) && strings.IndexByte("-+ #0", f[j]) != -1 {
+			j++
+		}
+		w := j
+		for j < len(f) && f[j] >= '0' && f[j] <= '9' {
+			j++
+		}
+		width, _ := strconv.Atoi(f[w:j])
+		prec := -1
+		if j < len(f) && f[j] == '.' {
+			p := j + 1
+			for j = p; j < len(f) && f[j] >= '0' && f[j] <= '9'; j++ {
+			}
+			prec, _ = strconv.Atoi(f[p:j])
+		}
+		if j >= len(f) {
+			return fmt.Errorf("hexdump-format: incomplete conversion %q", f[i:])
+		}
+
+		c := hdConv{spec: f[i:j], width: width}
+
+		switch f[j] {
+		case 'd', 'i':
+			c.kind, c.verb, c.signed, c.size = hdInt, 'd', true, 4
+		case 'u':
+			c.kind, c.verb, c.size = hdInt, 'd', 4
+		case 'o', 'x', 'X':
+			c.kind, c.verb, c.size = hdInt, f[j], 4
+		case 'e', 'E', 'f', 'g', 'G':
+			c.kind, c.verb, c.size = hdFloat, f[j], 8
+		case 'c':
+			c.kind, c.verb, c.size = hdChar, 's', 1
+		case 's':
+			c.kind, c.verb = hdString, 's'
+			c.size = prec
+			if count != -1 {
+				c.size = count
+			}
+			if c.size < 1 {
+				return fmt.Errorf("hexdump-format: %%s needs a byte count or a precision")
+			}
+		case '_':
+			j++
+			if j >= len(f) {
+				return fmt.Errorf("hexdump-format: incomplete conversion %q", f[i:])
+			}
+			switch f[j] {
+			case 'a', 'A':
+				c.kind = hdOffset
+				if f[j] == 'A' {
+					c.kind = hdEndOffset
+					u.final = true
+				}
+				j++
+				if j >= len(f) || strings.IndexByte("dox", f[j]) == -1 {
+					return fmt.Errorf("hexdump-format: %%_%c must be followed by d, o or x", f[j-1])
+				}
+				c.verb = f[j]
+			case 'c':
+				c.kind, c.verb, c.size = hdEscChar, 's', 1
+			case 'p':
+				c.kind, c.verb, c.size = hdPrint, 's', 1
+			case 'u':
+				c.kind, c.verb, c.size = hdCtrl, 's', 1
+			default:
+				return fmt.Errorf("hexdump-format: unknown conversion %%_%c", f[j])
+			}
+		default:
+			return fmt.Errorf("hexdump-format: unknown conversion %%%c", f[j])
+		}
+
+		// character conversions ignore the precision
+		if c.verb == 's' && c.kind != hdString {
+			c.spec = f[i:w]
+			if width > 0 {
+				c.spec += strconv.Itoa(width)
+			}
+		}
+
+		if c.size > 0 {
+			data++
+			if count != -1 && c.kind != hdString {
+				switch {
+				case c.kind == hdInt && (count == 1 || count == 2 || count == 4 || count == 8):
+				case c.kind == hdFloat && (count == 4 || count == 8):
+				case c.size == 1 && count == 1:
+				default:
+					return fmt.Errorf("hexdump-format: byte count %d does not work with %s", count, f[i:j+1])
+				}
+				c.size = count
+			}
+			u.size += c.size
+		}
+
+		u.convs = append(u.convs, c)
+		i = j
+	}
+	flushText()
+
+	if count != -1 && data > 1 {
+		return fmt.Errorf("hexdump-format: a byte count can only be used with a single conversion")
+	}
+	return nil
+}
+
+// writes a single conversion for the bytes in b, an empty b means the input ran out
+func (c *hdConv) write(w *bufio.Writer, b []byte, offset int64, color *Color) {
+	if c.kind == hdOffset || c.kind == hdEndOffset {
+		if USE_COLOR {
+			w.Write(GREY)
+		}
+		fmt.Fprintf(w, c.spec+string(c.verb), offset)
+		if USE_COLOR {
+			w.Write(CLEAR)
+		}
+		return
+	}
+
+	if len(b) < c.size {
+		// keep the columns aligned at the end of the input
+		w.WriteString(strings.Repeat(" ", c.width))
+		return
+	}
+	b = b[:c.size]
+
+	var s string
+	switch c.kind {
+	case hdInt:
+		var v uint64
+		switch c.size {
+		case 1:
+			v = uint64(b[0])
+		case 2:
+			v = uint64(binary.LittleEndian.Uint16(b))
+		case 4:
+			v = uint64(binary.LittleEndian.Uint32(b))
+		case 8:
+			v = binary.LittleEndian.Uint64(b)
+		}
+		if c.signed {
+			shift := 64 - 8*c.size
+			s = fmt.Sprintf(c.spec+string(c.verb), int64(v<<shift)>>shift)
+		} else {
+			s = fmt.Sprintf(c.spec+string(c.verb), v)
+		}
+	case hdFloat:
+		var v float64
+		if c.size == 4 {
+			v = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		} else {
+			v = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+		s = fmt.Sprintf(c.spec+string(c.verb), v)
+	case hdChar:
+		s = fmt.Sprintf(c.spec+"s", string(b))
+	case hdString:
+		if i := bytes.IndexByte(b, 0); i != -1 {
+			b = b[:i]
+		}
+		s = fmt.Sprintf(c.spec+"s", string(b))
+	case hdEscChar:
+		s = fmt.Sprintf(c.spec+"s", hdEscape(b[0]))
+	case hdPrint:
+		ch := "."
+		if b[0] > 0x1f && b[0] < 0x7f {
+			ch = string(b[:1])
+		}
+		s = fmt.Sprintf(c.spec+"s", ch)
+	case hdCtrl:
+		var name string
+		switch v := b[0]; {
+		case v < 0x20:
+			name = hdControlNames[v]
+		case v == 0x7f:
+			name = "del"
+		case v > 0x7f:
+			name = fmt.Sprintf("%02x", v)
+		default:
+			name = string(b[:1])
+		}
+		s = fmt.Sprintf(c.spec+"s", name)
+	}
+
+	if USE_COLOR && c.size == 1 {
+		pre, post := color.Colorize2(b[0])
+		w.Write(pre)
+		w.WriteString(s)
+		w.Write(post)
+		return
+	}
+	w.WriteString(s)
+}
+
+// the representation od -c and hexdump %_c use for a byte
+func hdEscape(b byte) string {
+	switch b {
+	case 0:
+		return `\0`
+	case '\a':
+		return `\a`
+	case '\b':
+		return `\b`
+	case '\f':
+		return `\f`
+	case '\n':
+		return `\n`
+	case '\r':
+		return `\r`
+	case '\t':
+		return `\t`
+	case '\v':
+		return `\v`
+	}
+	if b > 0x1f && b < 0x7f {
+		return string(rune(b))
+	}
+	return fmt.Sprintf("%03o", b)
+}
+
+// writes one format string for a block, units using %_A are only written when final is set
+func hdWriteUnits(w *bufio.Writer, units []hdUnit, block []byte, offset int64, final bool, color *Color) {
+	pos := 0
+	for _, u := range units {
+		if u.final != final {
+			continue
+		}
+
+		for it := 0; it < u.iter; it++ {
+			w.WriteString(u.lead)
+			for ci := range u.convs {
+				c := &u.convs[ci]
+				var b []byte
+				if pos < len(block) {
+					b = block[pos:]
+				}
+				c.write(w, b, offset+int64(pos), color)
+				pos += c.size
+
+				// the last iteration doesn't write trailing whitespace
+				text := c.text
+				if u.iter > 1 && it == u.iter-1 && ci == len(u.convs)-1 {
+					text = strings.TrimRight(text, " \t")
+				}
+				w.WriteString(text)
+			}
+		}
+	}
+}
+
+// HexdumpFormatDump renders r using hexdump(1) style format strings given with --hexdump-format
+// every format string is applied to each block of input, a block being the largest amount of
+// input consumed by any of the format strings. with --autoskip repeated blocks are shown as '*'
+func HexdumpFormatDump(r io.Reader, w io.Writer, color *Color) error {
+	var (
+		formats   [][]hdUnit
+		blockSize int
+	)
+
+	for _, f := range opts.HexdumpFmt {
+		units, err := parseHexdumpFormat(f)
+		if err != nil {
+			return err
+		}
+
+		size := 0
+		for _, u := range units {
+			if !u.final {
+				size += u.iter * u.size
+			}
+		}
+		blockSize = max(blockSize, size)
+		formats = append(formats, units)
+	}
+
+	var (
+		out     = bufio.NewWriter(w)
+		block   = make([]byte, blockSize)
+		prev    = make([]byte, blockSize)
+		offset  int64
+		dupe    bool
+		started bool
+		n       int
+		err     error
+	)
+	defer out.Flush()
+
+	if opts.Seek != -1 {
+		offset = opts.Seek
+	}
+
+	// only %_A and text, so all that matters is the length of the input
+	if blockSize == 0 {
+		size, err := io.Copy(io.Discard, r)
+		if err != nil {
+			return err
+		}
+		offset += size
+	}
+
+	r = bufio.NewReader(r)
+	for blockSize > 0 {
+		n, err = io.ReadFull(r, block)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+
+		if opts.Autoskip && started && n == blockSize && bytes.Equal(block, prev) {
+			if !dupe {
+				out.WriteString("*\n")
+				dupe = true
+			}
+			offset += int64(n)
+			continue
+		}
+		dupe = false
+		started = true
+		copy(prev, block)
+
+		for _, units := range formats {
+			hdWriteUnits(out, units, block[:n], offset, false, color)
+		}
+		offset += int64(n)
+	}
+
+	for _, units := range formats {
+		hdWriteUnits(out, units, nil, offset, true, color)
+	}
+
+	return out.Flush()
+}
@@ -9,33 +9,66 @@ import (
 	"log"
 	"os"
 	"path"
-	"strconv"
 	"strings"
 
 	"github.com/jessevdk/go-flags"
 )
 
 var opts struct {
-	OffsetFormat string `short:"t" long:"radix" default:"x" choice:"d" choice:"o" choice:"x" description:"Print offset in [d|o|x] format"`
-	Binary       bool   `short:"b" long:"binary" description:"output in binary format (01010101) incompatible with plain, reverse and include"`
-	Reverse      bool   `short:"r" long:"reverse" description:"re-assemble hexdump output back into binary"`
-	Autoskip     bool   `short:"a" long:"autoskip" description:"toggle autoskip (replaces blank lines with a *)"`
-	Bars         bool   `short:"B" long:"bars" description:"print delimiter bars in ascii table"`
-	Separator    string `          long:"separator" description:"separator character for the ascii character table"`
-	Seek         int64  `short:"s" long:"seek" description:"start at <seek> bytes"`
-	Len          int64  `short:"l" long:"len" description:"stop after <len> octets"`
-	Columns      int    `short:"c" long:"columns" description:"column count"`
-	GroupSize    int    `short:"g" long:"groups" description:"group size of bytes"`
-	Plain        bool   `short:"p" long:"plain" description:"plain output without ascii table and offset row [often used with hexxy -r]"`
-	Upper        bool   `short:"u" long:"upper" description:"output hex in UPPERCASE format"`
-	CInclude     bool   `short:"i" long:"include" description:"output in C include format"`
-	OutputFile   string `short:"o" long:"output" description:"automatically output to file instead of STDOUT"`
-	Color        string `short:"C" long:"color" default:"auto" choice:"always" choice:"auto" choice:"never" description:"this option forces color output [always|auto|never]"`
-	NoColor      bool   `short:"n" long:"no-color" description:"do not print output with color"`
-	Verbose      bool   `short:"v" long:"verbose" description:"print debugging information and verbose output"`
-	WriteConfig  bool   `short:"W" long:"create-config" description:"create the default config file"`
-	NoConfig     bool   `short:"N" long:"no-config" description:"create the default config file"`
-	AsciiColor   bool   `short:"A" long:"no-ascii-color" description:"use color in the ascii table"`
+	OffsetFormat string   `short:"t" long:"radix" default:"x" choice:"d" choice:"o" choice:"x" description:"Print offset in [d|o|x] format"`
+	Binary       bool     `short:"b" long:"binary" description:"output in binary format (01010101) incompatible with plain, reverse and include"`
+	Reverse      bool     `short:"r" long:"reverse" description:"re-assemble hexdump output back into binary"`
+	Autoskip     bool     `short:"a" long:"autoskip" description:"toggle autoskip (replaces blank lines with a *)"`
+	Bars         bool     `short:"B" long:"bars" description:"print delimiter bars in ascii table"`
+	Separator    string   `          long:"separator" description:"separator character for the ascii character table"`
+	Seek         int64    `short:"s" long:"seek" description:"start at <seek> bytes"`
+	Len          int64    `short:"l" long:"len" description:"stop after <len> octets"`
+	Columns      int      `short:"c" long:"columns" description:"column count"`
+	GroupSize    int      `short:"g" long:"groups" description:"group size of bytes"`
+	Plain        bool     `short:"p" long:"plain" description:"plain output without ascii table and offset row [often used with hexxy -r]"`
+	Upper        bool     `short:"u" long:"upper" description:"output hex in UPPERCASE format"`
+	CInclude     bool     `short:"i" long:"include" description:"output in C include format"`
+	OutputFile   string   `short:"o" long:"output" description:"automatically output to file instead of STDOUT"`
+	Color        string   `short:"C" long:"color" default:"auto" choice:"always" choice:"auto" choice:"never" description:"this option forces color output [always|auto|never]"`
+	NoColor      bool     `short:"n" long:"no-color" description:"do not print output with color"`
+	Verbose      bool     `short:"v" long:"verbose" description:"print debugging information and verbose output"`
+	WriteConfig  bool     `short:"W" long:"create-config" description:"create the default config file"`
+	NoConfig     bool     `short:"N" long:"no-config" description:"create the default config file"`
+	AsciiColor   bool     `short:"A" long:"no-ascii-color" description:"use color in the ascii table"`
+	IntelHex     bool     `          long:"ihex" description:"output in Intel HEX format, record length is set with --columns"`
+	Srec         bool     `          long:"srec" description:"output in Motorola S-record format, record length is set with --columns"`
+	SrecType     string   `          long:"srec-type" default:"auto" choice:"auto" choice:"19" choice:"28" choice:"37" description:"S-record address width [auto|19|28|37]"`
+	Encode       string   `          long:"encode" choice:"base64" choice:"base64url" choice:"base32" choice:"ascii85" choice:"uuencode" description:"output in a text encoding, wrapped at --columns [base64|base64url|base32|ascii85|uuencode]"`
+	Escape       string   `          long:"escape" choice:"c" choice:"python" choice:"go" choice:"js" description:"output escaped \\xNN string literals for a language [c|python|go|js]"`
+	BadChars     string   `          long:"bad-chars" description:"highlight and report bytes that must not appear, e.g. 00,0a,0d"`
+	Asm          string   `          long:"asm" choice:"gas" choice:"nasm" choice:"armasm" description:"output assembler data directives [gas|nasm|armasm]"`
+	Align        int      `          long:"align" description:"alignment directive written before the --asm data block"`
+	HDL          string   `          long:"hdl" choice:"verilog" choice:"vhdl" choice:"mif" choice:"coe" description:"output a memory initialisation file, --columns sets words per line [verilog|vhdl|mif|coe]"`
+	WordWidth    int      `          long:"word-width" description:"word width in bytes for --hdl output (default 1)"`
+	Endian       string   `          long:"endian" default:"big" choice:"big" choice:"little" description:"byte order of --hdl words [big|little]"`
+	Format       string   `          long:"format" choice:"json" choice:"ndjson" choice:"csv" choice:"tsv" choice:"html" choice:"markdown" description:"output as structured data or a document [json|ndjson|csv|tsv|html|markdown]"`
+	Highlight    []string `          long:"highlight" description:"highlight a range in html output, START-END or START+LEN (can be repeated)"`
+	Annotate     []string `          long:"annotate" description:"add a note to a range in markdown output, START-END:TEXT or START+LEN:TEXT (can be repeated)"`
+	MDStyle      string   `          long:"markdown-style" default:"table" choice:"table" choice:"code" description:"render markdown output as a table or a fenced code block [table|code]"`
+	PerByte      bool     `          long:"per-byte" description:"write one csv/tsv record per byte instead of per row"`
+	Template     string   `          long:"template" description:"render every row with a text/template, given as a file or a string with {{ actions }}"`
+	TmplHeader   string   `          long:"header-template" description:"template rendered once before the rows, given as a file or a string with {{ actions }}"`
+	TmplFooter   string   `          long:"footer-template" description:"template rendered once after the rows, given as a file or a string with {{ actions }}"`
+	HexdumpFmt   []string `          long:"hexdump-format" unquote:"false" description:"format rows like hexdump -e, e.g. '16/1 \"%02x \" \"\\n\"' (can be repeated)"`
+	Text         string   `          long:"text" default:"ascii" choice:"ascii" choice:"utf8" choice:"utf16le" choice:"utf16be" choice:"latin1" description:"decode the text column as [ascii|utf8|utf16le|utf16be|latin1]"`
+	Codepage     string   `          long:"codepage" choice:"cp437" choice:"cp037" choice:"cp1047" description:"decode the text column with a code page, cp437 shows a glyph for every byte [cp437|cp037|cp1047]"`
+	ColorDepth   string   `          long:"color-depth" default:"auto" choice:"auto" choice:"16" choice:"256" choice:"truecolor" description:"colors the terminal can show, auto checks COLORTERM, TERM and terminfo [auto|16|256|truecolor]"`
+	Theme        string   `          long:"theme" choice:"gradient" choice:"category" choice:"monochrome-bold" choice:"solarized" choice:"colourblind-safe" description:"color theme [gradient|category|monochrome-bold|solarized|colourblind-safe]"`
+	Glyphs       bool     `          long:"glyphs" description:"show control pictures and markers instead of '.' and color bytes by category"`
+	BaseAddr     string   `          long:"base-addr" description:"start address for ihex/srec/hdl output, or the address the reversed image starts at"`
+	EntryAddr    string   `          long:"entry" description:"write a start address record with this entry point"`
+	Fill         string   `          long:"fill" default:"0xff" description:"byte used to fill gaps between ihex and srec records when reversing, hdl gaps are zeros"`
+	Jobs         int      `          long:"jobs" default:"1" description:"render regular files in chunks on N workers, the output is the same as with one"`
+	Concat       bool     `          long:"concat" description:"dump all input files as one continuous stream with offsets across files"`
+	Decompress   string   `          long:"decompress" choice:"auto" choice:"gzip" choice:"zlib" choice:"bzip2" choice:"lzw" choice:"flate" description:"decompress the input before dumping, auto detects gzip, zlib and bzip2 [auto|gzip|zlib|bzip2|lzw|flate]"`
+	MarkStreams  bool     `          long:"mark-streams" description:"mark where every compressed stream starts in --decompress hex and binary dumps"`
+	Member       string   `          long:"member" description:"dump a file inside a zip or tar archive, also given as ARCHIVE:MEMBER"`
+	ListMembers  bool     `          long:"list-members" description:"list the files in a zip or tar archive with their sizes and offsets"`
 }
 
 var Debug = func(string, ...interface{}) {}
@@ -45,6 +78,18 @@ const (
 	dumpBinary
 	dumpCformat
 	dumpPlain
+	dumpIntelHex
+	dumpSrec
+	dumpEncoded
+	dumpEscaped
+	dumpAsm
+	dumpHDL
+	dumpJSON
+	dumpCSV
+	dumpHTML
+	dumpMarkdown
+	dumpTemplate
+	dumpHexdumpFmt
 )
 
 const (
@@ -64,10 +109,17 @@ var (
 	lenEquals    = []byte("_len = ")
 	brackets     = []byte("[] = {")
 	asterisk     = []byte("*")
+	holeLine     = []byte("* hole ")
+	streamLine   = []byte(" stream ")
+	compressedAt = []byte(", compressed ")
+	fileStart    = []byte("==> ")
+	fileEnd      = []byte(" <==\n")
 	commaSpace   = []byte(", ")
 	comma        = []byte(",")
 	semiColonNl  = []byte(";\n")
 	bar          = []byte("┊")
+	binaryOne    = []byte("\x1b[32m")
+	binaryZero   = []byte("\x1b[34m")
 )
 
 var (
@@ -85,335 +137,65 @@ func outputIsPipe() bool {
 }
 
 func HexxyDump(r io.Reader, w io.Writer, filename string, color *Color) error {
-	var (
-		lineOffset  int64
-		hexOffset   = make([]byte, 6)
-		groupSize   int
-		cols        int
-		octs        int
-		caps        = ldigits
-		doCheader   = true
-		doCEnd      bool
-		varDeclChar = make([]byte, 14+len(filename)+6) // for "unsigned char NAME_FORMAT[] = {"
-		varDeclInt  = make([]byte, 16+len(filename)+7) // enough room for "unsigned int NAME_FORMAT = "
-		nulLine     int64
-		totalOcts   int64
-		colFmt      int
-	)
-
-	if dumpType == dumpCformat {
-		_ = copy(varDeclChar[0:14], unsignedChar[:])
-		_ = copy(varDeclInt[0:16], unsignedInt[:])
-
-		for i := 0; i < len(filename); i++ {
-			if !isSpecial(filename[i]) {
-				varDeclChar[14+i] = filename[i]
-				varDeclInt[16+i] = filename[i]
-			} else {
-				varDeclChar[14+i] = '_'
-				varDeclInt[16+i] = '_'
-			}
-		}
-		// copy "[] = {" and "_len = "
-		_ = copy(varDeclChar[14+len(filename):], brackets[:])
-		_ = copy(varDeclInt[16+len(filename):], lenEquals[:])
-	}
-
-	if opts.Upper {
-		caps = udigits
-	}
-
-	if opts.Columns == -1 {
-		switch dumpType {
-		case dumpPlain:
-			cols = 30
-		case dumpCformat:
-			cols = 12
-		case dumpBinary:
-			cols = 6
-		default:
-			cols = 16
-		}
-	} else {
-		cols = opts.Columns
-	}
-
 	switch dumpType {
-	case dumpBinary:
-		octs = 8
-		groupSize = 1
-	case dumpPlain:
-		octs = 0
-	case dumpCformat:
-		octs = 4
-	default:
-		octs = 2
-		groupSize = 2
-	}
-
-	if opts.GroupSize != -1 {
-		groupSize = opts.GroupSize
+	case dumpIntelHex:
+		return IntelHexDump(r, w)
+	case dumpSrec:
+		return SrecDump(r, w, filename)
+	case dumpEncoded:
+		return EncodeDump(r, w, filename)
+	case dumpEscaped:
+		return EscapeDump(r, w, filename)
+	case dumpAsm:
+		return AsmDump(r, w, filename)
+	case dumpHDL:
+		return HDLDump(r, w, filename)
+	case dumpHexdumpFmt:
+		return HexdumpFormatDump(r, w, color)
+	}
+
+	d, err := newDumper(filename, color)
+	if err != nil {
+		return err
 	}
 
-	if opts.Len != -1 {
-		if opts.Len < int64(cols) {
-			cols = int(opts.Len)
+	// the structured formats are built on the rows of the dumper
+	if dumpType >= dumpJSON {
+		if in, ok := r.(*mappedFile); ok {
+			d.setHoles(in.holes)
 		}
 	}
-
-	if octs < 1 {
-		octs = cols
-	}
-
-	switch opts.OffsetFormat {
-	case "d":
-		colFmt = 10
-	case "o":
-		colFmt = 8
-	case "x":
-		fallthrough
-	default:
-		colFmt = 16
-	}
-
-	// allocate their size based on the users specs, hence why its declared here
-	var (
-		line = make([]byte, cols)
-		char = make([]byte, octs)
-	)
-
-	c := int64(0)
-	nl := int64(0)
-	r = bufio.NewReader(r)
-
-	var (
-		n   int
-		err error
-	)
-
-	for {
-		n, err = io.ReadFull(r, line)
-		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
-			return fmt.Errorf("hexxy: %v", err)
-		}
-
-		// we check early on for if the dump type is "plain" (no formatting, its just a stream of bytes)
-		// and we don't have to do any hard work
-		if dumpType == dumpPlain && n != 0 {
-			for i := 0; i < n; i++ {
-				hexEncode(char, line[i:i+1], caps)
-				w.Write(char)
-				c++
-			}
-			continue
-		}
-
-		// write the line ending based on the dump "mode"
-		if n == 0 {
-			if dumpType == dumpPlain {
-				w.Write(newLine)
-			}
-
-			if dumpType == dumpCformat {
-				doCEnd = true
-			} else {
-				return nil
-			}
-		}
-
-		if opts.Len != -1 {
-			if totalOcts == opts.Len {
-				break
-			}
-			totalOcts += opts.Len
-		}
-
-		if opts.Autoskip && isEmpty(&line) {
-			if nulLine == 1 {
-				w.Write(asterisk)
-				w.Write(newLine)
-			}
-
-			nulLine++
-
-			if nulLine > 1 {
-				lineOffset++ // still increment offset while printing crunched lines with '*'
-				continue
-			}
-		}
-
-		// hex or binary formats only
-		// writing the 0000000: part
-		if dumpType <= dumpBinary {
-			// create line offset
-			hexOffset = strconv.AppendInt(hexOffset[0:0], lineOffset, colFmt)
-
-			// confusing looking but we are just "slicing" our zero padding buffer and our offset byte buffer together
-			// ie zeroHeader = 0000000: and hexOffset = 10 -- we are just inserting that 10 in this position '0000010:'
-			// based on the offsets length
-			if USE_COLOR {
-				w.Write([]byte(GREY))
-				w.Write(zeroHeader[0:(6 - len(hexOffset))])
-				w.Write(hexOffset)
-				w.Write(zeroHeader[6:])
-				w.Write([]byte(CLEAR))
-			} else {
-				w.Write(zeroHeader[0:(6 - len(hexOffset))])
-				w.Write(hexOffset)
-				w.Write(zeroHeader[6:])
-			}
-
-			lineOffset++
-		} else if doCheader {
-			w.Write(varDeclChar)
-			w.Write(newLine)
-			doCheader = false
-		}
-
-		if dumpType == dumpBinary {
-			// dump binary values
-			for i, k := 0, octs; i < n; i, k = i+1, k+octs {
-				binaryEncode(char, line[i:i+1])
-
-				if USE_COLOR {
-					for _, b := range char {
-						if b == '1' {
-							w.Write([]byte("\x1b[32m"))
-							w.Write([]byte{b})
-							w.Write([]byte(CLEAR))
-						} else {
-							w.Write([]byte("\x1b[34m"))
-							w.Write([]byte{b})
-							w.Write([]byte(CLEAR))
-						}
-					}
-					// w.Write(char)
-					c++
-				} else {
-					w.Write(char)
-					c++
-				}
-
-				if k == octs*groupSize {
-					k = 0
-					w.Write(space)
-				}
-			}
-		} else if dumpType == dumpCformat {
-			// dump C format
-			if !doCEnd {
-				w.Write(doubleSpace)
-			}
-			for i := 0; i < n; i++ {
-				cfmtEncode(char, line[i:i+1], caps)
-				w.Write(char)
-				c++
-				// no space at EOL
-				if i != n-1 {
-					w.Write(commaSpace)
-				} else if n == cols {
-					w.Write(comma)
-				}
-			}
-		} else {
-			// hex values -- default
-			for i, k := 0, octs; i < n; i, k = i+1, k+octs {
-				hexEncode(char, line[i:i+1], caps)
-
-				if USE_COLOR {
-					i := line[i : i+1][0]
-					b, c := color.Colorize2(i)
-					w.Write(b)
-					w.Write(char)
-					w.Write(c)
-				} else {
-					w.Write(char)
-				}
-				c++
-
-				if k == octs*groupSize {
-					k = 0
-					w.Write(space)
-				}
-			}
-		}
-
-		if doCEnd {
-			w.Write(varDeclInt)
-			w.Write([]byte(strconv.FormatInt(c, 10)))
-			w.Write(semiColonNl)
-			return nil
-		}
-
-		if n < len(line) && dumpType <= dumpBinary {
-			for i := n * octs; i < len(line)*octs; i++ {
-				w.Write(space)
-
-				if i%octs == 1 {
-					w.Write(space)
-				}
-			}
+	switch dumpType {
+	case dumpJSON:
+		return JSONDump(d, r, w, filename)
+	case dumpCSV:
+		return CSVDump(d, r, w)
+	case dumpHTML:
+		return HTMLDump(d, r, w, filename)
+	case dumpMarkdown:
+		return MarkdownDump(d, r, w)
+	case dumpTemplate:
+		return TemplateDump(d, r, w, filename)
+	}
+
+	// regular files can be split into chunks that are rendered at the same time
+	switch in := r.(type) {
+	case *decompressor:
+		if opts.MarkStreams && dumpType <= dumpBinary {
+			d.compressed = in
 		}
-
-		if dumpType != dumpCformat {
-			w.Write(space)
+	case *mappedFile:
+		d.setHoles(in.holes)
+		if opts.Jobs > 1 {
+			return d.parallel(in, in.Size(), w, opts.Jobs)
 		}
-
-		if dumpType <= dumpBinary {
-			// character values
-			b := line[:n]
-			// |hello,.world!|
-			if opts.Bars {
-				if USE_COLOR {
-					w.Write([]byte(GREY))
-					w.Write(bar)
-					w.Write(CLEAR)
-				} else {
-					w.Write(bar)
-				}
-			}
-
-			var v byte
-			for i := 0; i < len(b); i++ {
-				v = b[i]
-
-				if USE_COLOR && !opts.AsciiColor {
-					if v > 0x1f && v < 0x7f {
-						charByte := line[i : i+1][0]
-						b, c := color.Colorize2(charByte)
-						w.Write(b)
-						w.Write(line[i : i+1])
-						w.Write(c)
-					} else {
-						w.Write([]byte(GREY))
-						w.Write(dot)
-						w.Write(CLEAR)
-					}
-				} else {
-					if v > 0x1f && v < 0x7f {
-						w.Write(line[i : i+1])
-					} else {
-						w.Write(dot)
-					}
-				}
-			}
-
-			if opts.Bars {
-				if USE_COLOR {
-					w.Write([]byte(GREY))
-					w.Write(bar)
-					w.Write(CLEAR)
-				} else {
-					w.Write(bar)
-				}
-			}
+		return d.slice(in.data, w)
+	case *io.SectionReader:
+		if opts.Jobs > 1 {
+			return d.parallel(in, in.Size(), w, opts.Jobs)
 		}
-
-		w.Write(newLine)
-		nl++
 	}
-
-	return nil
+	return d.stream(r, w)
 }
 
 func Hexxy(args []string) error {
@@ -424,28 +206,27 @@ func Hexxy(args []string) error {
 	}
 
 	if !color.disable {
-		color.Compute() // precompute this at compile time?
+		// --glyphs goes together with the category colors unless a theme was picked
+		theme := opts.Theme
+		if theme == "" && opts.Glyphs {
+			theme = "category"
+		}
+		if err := color.LoadTheme(theme); err != nil {
+			return err
+		}
 	}
 
 	var (
-		infile  *os.File
+		names   []string
 		outfile *os.File
 		err     error
 	)
 
+	// an empty name is stdin
 	if len(args) < 1 && inputIsPipe() {
-		infile = os.Stdin
+		names = []string{""}
 	} else {
-		infile, err = os.Open(args[0])
-		if err != nil {
-			return fmt.Errorf("hexxy: %v", err.Error())
-		}
-	}
-
-	defer infile.Close()
-
-	if opts.Seek != -1 {
-		_, err = infile.Seek(opts.Seek, io.SeekStart)
+		names, err = expandGlobs(args)
 		if err != nil {
 			return fmt.Errorf("hexxy: %v", err.Error())
 		}
@@ -468,6 +249,30 @@ func Hexxy(args []string) error {
 		dumpType = dumpCformat
 	case opts.Plain:
 		dumpType = dumpPlain
+	case opts.IntelHex:
+		dumpType = dumpIntelHex
+	case opts.Srec:
+		dumpType = dumpSrec
+	case opts.Encode != "":
+		dumpType = dumpEncoded
+	case opts.Escape != "":
+		dumpType = dumpEscaped
+	case opts.Asm != "":
+		dumpType = dumpAsm
+	case opts.HDL != "":
+		dumpType = dumpHDL
+	case opts.Format == "json", opts.Format == "ndjson":
+		dumpType = dumpJSON
+	case opts.Format == "csv", opts.Format == "tsv":
+		dumpType = dumpCSV
+	case opts.Format == "html":
+		dumpType = dumpHTML
+	case opts.Format == "markdown":
+		dumpType = dumpMarkdown
+	case opts.Template != "":
+		dumpType = dumpTemplate
+	case len(opts.HexdumpFmt) > 0:
+		dumpType = dumpHexdumpFmt
 	default:
 		dumpType = dumpHex
 	}
@@ -475,20 +280,120 @@ func Hexxy(args []string) error {
 	out := bufio.NewWriter(outfile)
 	defer out.Flush()
 
-	if opts.Reverse {
-		if err := HexxyReverse(infile, outfile); err != nil {
+	// the other formats write a whole document, a second one would end up after its end
+	if len(names) > 1 && dumpType > dumpPlain && !opts.Concat && !opts.Reverse && !opts.ListMembers {
+		return fmt.Errorf("hexxy: %d input files, this output format takes a single input, use --concat to dump them as one", len(names))
+	}
+
+	if opts.Concat && len(names) > 1 {
+		if err := hexxyConcat(names, out, outfile, color); err != nil {
 			return fmt.Errorf("hexxy: %v", err.Error())
 		}
 		return nil
 	}
 
-	if err := HexxyDump(infile, out, infile.Name(), color); err != nil {
-		return fmt.Errorf("hexxy: %v", err.Error())
+	for i, name := range names {
+		infile := os.Stdin
+		member := opts.Member
+		if name != "" {
+			file, m := splitMember(name)
+			if m != "" {
+				member = m
+			}
+			infile, err = os.Open(file)
+			if err != nil {
+				return fmt.Errorf("hexxy: %v", err.Error())
+			}
+		}
+
+		if len(names) > 1 && !opts.Reverse {
+			err = fileHeader(out, name, i == 0)
+		}
+		if err == nil {
+			err = hexxyFile(infile, member, out, outfile, color)
+		}
+		infile.Close()
+		if err != nil {
+			return fmt.Errorf("hexxy: %v", err.Error())
+		}
 	}
 
 	return nil
 }
 
+// dumps or reverses a single input file, or a member of it when it is an archive
+func hexxyFile(infile *os.File, member string, out io.Writer, outfile *os.File, color *Color) error {
+	if (member != "" || opts.ListMembers) && !opts.Reverse {
+		return hexxyArchive(infile, member, out, color)
+	}
+
+	if opts.Decompress != "" && !opts.Reverse {
+		in, err := decompressInput(infile)
+		if err != nil {
+			return err
+		}
+		return HexxyDump(in, out, infile.Name(), color)
+	}
+
+	if opts.Seek != -1 {
+		if _, err := infile.Seek(opts.Seek, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	if opts.Reverse {
+		return HexxyReverse(infile, outfile)
+	}
+
+	// --len is applied to the input so every dump type stops at the same place
+	in, release, err := dumpInput(infile)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return HexxyDump(in, out, infile.Name(), color)
+}
+
+// dumps or reverses the files one after the other as if they were a single file
+// --seek and --len apply to the whole stream
+func hexxyConcat(names []string, out io.Writer, outfile *os.File, color *Color) error {
+	readers := make([]io.Reader, 0, len(names))
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+
+	var in io.Reader = io.MultiReader(readers...)
+	if opts.Decompress != "" && !opts.Reverse {
+		in, err := decompressInput(in)
+		if err != nil {
+			return err
+		}
+		return HexxyDump(in, out, names[0], color)
+	}
+
+	if opts.Seek != -1 {
+		if _, err := io.CopyN(io.Discard, in, opts.Seek); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+
+	if opts.Reverse {
+		return HexxyReverse(in, outfile)
+	}
+
+	if opts.Len != -1 {
+		in = io.LimitReader(in, opts.Len)
+	}
+	// the C variable is named after the first file
+	return HexxyDump(in, out, names[0], color)
+}
+
 const usage_msg = `
 hexxy is a command line hex dumping tool
 
@@ -509,6 +414,10 @@ Examples:
 
 	# Seek to N bytes in an input file
 	hexxy -s 12546 input-file
+
+	# Dump several files, or one stream made of all of them
+	hexxy input-file other-file
+	hexxy --concat 'parts/*.bin'
 `
 
 // extra usage examples
@@ -542,11 +451,22 @@ func useColor() bool {
 	return false
 }
 
+// html colors are css and not terminal escapes, so the page is colored when it's written to a pipe
+// but NO_COLOR, --no-color and --color never still turn them off
+func htmlColor() bool {
+	if HasNoColorEnvVar() || opts.NoColor {
+		return false
+	}
+	return strings.ToLower(opts.Color) != "never"
+}
+
 func init() {
 	opts.Seek = -1 // default no-op values
 	opts.Columns = -1
 	opts.GroupSize = -1
 	opts.Len = -1
+	opts.WordWidth = -1
+	opts.Align = -1
 }
 
 func configPath() string {
@@ -597,6 +517,9 @@ func noConfig() bool {
 
 func main() {
 	parser := flags.NewParser(&opts, flags.Default)
+	if _, err := parser.AddGroup("Theme", "", &themeOpts); err != nil {
+		log.Fatal(err)
+	}
 
 	if !noConfig() {
 		ini := flags.NewIniParser(parser)
@@ -630,6 +553,7 @@ func main() {
 
 	// set color based on flags or default to off
 	USE_COLOR = useColor()
+	setColorDepth(opts.ColorDepth)
 
 	if !inputIsPipe() && len(args) == 0 {
 		parser.WriteHelp(os.Stderr)
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+)
+
+// a range of a sparse file that has no data on disk and reads as zeros
+type hole struct {
+	start, end int64
+}
+
+// keeps the holes that cover at least two whole rows, rounded to whole rows
+// the hex and binary dumps skip them without rendering the zeros, the formats
+// built on rows collapse them
+func (d *dumper) setHoles(holes []hole) {
+	if dumpType == dumpCformat || dumpType == dumpPlain {
+		return
+	}
+
+	cols := int64(d.cols)
+	for _, h := range holes {
+		start := (h.start + cols - 1) / cols * cols
+		end := h.end / cols * cols
+		if end-start >= 2*cols {
+			d.holes = append(d.holes, hole{start, end})
+		}
+	}
+}
+
+// the index of the first hole that ends after offset
+func (d *dumper) holeAfter(offset int64) int {
+	return sort.Search(len(d.holes), func(i int) bool { return d.holes[i].end > offset })
+}
+
+// the hole the next row starts in, if any
+func (d *dumper) nextHole() (hole, bool) {
+	for d.hole < len(d.holes) && d.holes[d.hole].end <= d.offset {
+		d.hole++
+	}
+	if d.hole == len(d.holes) || d.holes[d.hole].start > d.offset {
+		return hole{}, false
+	}
+	return d.holes[d.hole], true
+}
+
+// skips the hole the next row starts in, a hole is announced by the row it starts at
+// reports whether there was a hole
+func (d *dumper) skipHole() bool {
+	h, ok := d.nextHole()
+	if !ok {
+		return false
+	}
+
+	if h.start == d.offset {
+		// * hole 0x10000000-0x7fff0000
+		d.cw.plain(holeLine)
+		d.appendOffset(d.start + h.start)
+		d.cw.buf = append(d.cw.buf, '-')
+		d.appendOffset(d.start + h.end)
+		d.cw.buf = append(d.cw.buf, newLine...)
+	}
+
+	d.offset = h.end
+	d.text.skip(d.offset)
+	if opts.Autoskip {
+		// the hole counts as skipped empty rows, so the zeros after it stay hidden as well
+		d.nulLine = 2
+	}
+	return true
+}
+
+// appends an offset in the --radix format with its prefix
+func (d *dumper) appendOffset(offset int64) {
+	switch d.colFmt {
+	case 16:
+		d.cw.buf = append(d.cw.buf, "0x"...)
+	case 8:
+		d.cw.buf = append(d.cw.buf, '0')
+	}
+	d.cw.buf = strconv.AppendInt(d.cw.buf, offset, d.colFmt)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lseek whence values that find the data and the holes of a sparse file
+const (
+	seekData = 3
+	seekHole = 4
+)
+
+// finds the holes of f between offset and offset+size, relative to offset
+// file systems without sparse files report none
+func findHoles(f *os.File, offset, size int64) []hole {
+	var (
+		holes []hole
+		end   = offset + size
+	)
+
+	for off := offset; off < end; {
+		data, err := f.Seek(off, seekData)
+		if errors.Is(err, syscall.ENXIO) {
+			// nothing but holes up to the end of the file
+			data = end
+		} else if err != nil {
+			return holes
+		}
+
+		if data > off {
+			holes = append(holes, hole{off - offset, min(data, end) - offset})
+		}
+		if data >= end {
+			break
+		}
+
+		off, err = f.Seek(data, seekHole)
+		if err != nil {
+			return holes
+		}
+	}
+
+	return holes
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// a 2 MiB file with data at the start of its first and second MiB and holes after both
+func sparseFile(t *testing.T) *os.File {
+	f, err := os.Create(filepath.Join(t.TempDir(), "sparse.img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	if _, err := f.WriteAt([]byte("DATA"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteAt([]byte("TAIL"), 1<<20); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(2 << 20); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []hole{{4096, 1 << 20}, {1<<20 + 4096, 2 << 20}}
+	if holes := findHoles(f, 0, 2<<20); !reflect.DeepEqual(holes, want) {
+		t.Skipf("the file system reports holes %v instead of %v", holes, want)
+	}
+	return f
+}
+
+// dumps f like hexxy with --seek and --autoskip
+func dumpSparse(t *testing.T, f *os.File, seek int64, autoskip bool) string {
+	saved := opts
+	t.Cleanup(func() { opts = saved })
+	opts.Seek, opts.Autoskip = seek, autoskip
+	dumpType, USE_COLOR = dumpHex, false
+
+	if _, err := f.Seek(max(seek, 0), 0); err != nil {
+		t.Fatal(err)
+	}
+	in, release, err := dumpInput(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	var out bytes.Buffer
+	if err := HexxyDump(in, &out, f.Name(), &Color{disable: true}); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestHoles(t *testing.T) {
+	f := sparseFile(t)
+
+	tests := []struct {
+		seek     int64
+		autoskip bool
+		want     string
+	}{
+		{-1, true, `0000000: 4441 5441 0000 0000 0000 0000 0000 0000  DATA............
+0000010: 0000 0000 0000 0000 0000 0000 0000 0000  ................
+*
+* hole 0x1000-0x100000
+0100000: 5441 494c 0000 0000 0000 0000 0000 0000  TAIL............
+0100010: 0000 0000 0000 0000 0000 0000 0000 0000  ................
+*
+* hole 0x101000-0x200000
+`},
+		// offsets stay absolute after --seek
+		{2048, true, `0000800: 0000 0000 0000 0000 0000 0000 0000 0000  ................
+*
+* hole 0x1000-0x100000
+0100000: 5441 494c 0000 0000 0000 0000 0000 0000  TAIL............
+0100010: 0000 0000 0000 0000 0000 0000 0000 0000  ................
+*
+* hole 0x101000-0x200000
+`},
+		// a seek into a hole starts with what is left of it
+		{0x80000, true, `* hole 0x80000-0x100000
+0100000: 5441 494c 0000 0000 0000 0000 0000 0000  TAIL............
+0100010: 0000 0000 0000 0000 0000 0000 0000 0000  ................
+*
+* hole 0x101000-0x200000
+`},
+	}
+
+	for _, tt := range tests {
+		if got := dumpSparse(t, f, tt.seek, tt.autoskip); got != tt.want {
+			t.Errorf("-s %d -a %v:\n%s\nwant:\n%s", tt.seek, tt.autoskip, got, tt.want)
+		}
+	}
+
+	// without --autoskip the zeros around the holes are dumped and the holes are still skipped
+	got := dumpSparse(t, f, -1, false)
+	for _, line := range []string{
+		"0000ff0: 0000 0000 0000 0000 0000 0000 0000 0000  ................\n* hole 0x1000-0x100000\n0100000: 5441 494c",
+		"0100ff0: 0000 0000 0000 0000 0000 0000 0000 0000  ................\n* hole 0x101000-0x200000\n",
+	} {
+		if !strings.Contains(got, line) {
+			t.Errorf("-s -1 -a false: no %q in the dump", line)
+		}
+	}
+	if !strings.HasSuffix(got, "* hole 0x101000-0x200000\n") {
+		t.Errorf("-s -1 -a false: the dump does not end with the last hole")
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// a half open range of absolute offsets
+type byteRange struct {
+	start, end int64
+}
+
+// parses ranges in the form of START-END (inclusive) or START+LEN
+func parseRange(s string) (byteRange, error) {
+	if a, b, ok := strings.Cut(s, "+"); ok {
+		start, err := parseNumber(a, 63)
+		if err != nil {
+			return byteRange{}, err
+		}
+		n, err := parseNumber(b, 63)
+		if err != nil {
+			return byteRange{}, err
+		}
+		return byteRange{int64(start), int64(start + n)}, nil
+	}
+
+	if a, b, ok := strings.Cut(s, "-"); ok {
+		start, err := parseNumber(a, 63)
+		if err != nil {
+			return byteRange{}, err
+		}
+		end, err := parseNumber(b, 63)
+		if err != nil {
+			return byteRange{}, err
+		}
+		if end < start {
+			return byteRange{}, fmt.Errorf("range %q ends before it starts", s)
+		}
+		return byteRange{int64(start), int64(end) + 1}, nil
+	}
+
+	return byteRange{}, fmt.Errorf("invalid range %q, expected START-END or START+LEN", s)
+}
+
+func inRanges(ranges []byteRange, off int64) bool {
+	for _, r := range ranges {
+		if off >= r.start && off < r.end {
+			return true
+		}
+	}
+	return false
+}
+
+const htmlHeader = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { background: #1d1f21; color: #c5c8c6; }
+pre { font-family: ui-monospace, "DejaVu Sans Mono", Menlo, Consolas, monospace; font-size: 14px; line-height: 1.3; }
+a.off { color: #6f6f6f; text-decoration: none; }
+a.off:hover { text-decoration: underline; }
+span.row:target { background: #373b41; }
+.g { color: #6f6f6f; }
+.hl { outline: 1px solid #f0c674; background: #5f5f00; }
+`
+
+// writes a css class for every color of the 256 color palette, mirroring Color.Compute
+func writePalette(w io.Writer) {
+	for i := 0; i < 256; i++ {
+		r, g, b := xtermRGB(i)
+		if lowVisibility(i) {
+			fmt.Fprintf(w, ".c%d { color: #eeeeee; background: #%02x%02x%02x; font-weight: bold; }\n", i, r, g, b)
+		} else {
+			fmt.Fprintf(w, ".c%d { color: #%02x%02x%02x; }\n", i, r, g, b)
+		}
+	}
+}
+
+// HTMLDump writes r as a self contained html page using the same layout as the hex dump
+// every row has an anchor named after its offset (#0x1f00), every byte a tooltip with
+// its offset and value, and --highlight ranges are outlined
+func HTMLDump(d *dumper, r io.Reader, w io.Writer, filename string) error {
+	var ranges []byteRange
+	for _, s := range opts.Highlight {
+		br, err := parseRange(s)
+		if err != nil {
+			return err
+		}
+		ranges = append(ranges, br)
+	}
+
+	var (
+		color = htmlColor()
+		sep   = html.EscapeString(string(bar))
+		buf   []byte
+	)
+
+	fmt.Fprintf(w, htmlHeader, html.EscapeString(path.Base(filename)))
+	writePalette(w)
+	io.WriteString(w, "</style>\n</head>\n<body>\n<pre>\n")
+
+	// opens a span for a byte, the class list depends on color and highlighting
+	openSpan := func(buf []byte, v byte, off int64, tooltip bool) []byte {
+		buf = append(buf, "<span"...)
+		hl := inRanges(ranges, off)
+		if color || hl {
+			buf = append(buf, ` class="`...)
+			if color {
+				buf = append(buf, 'c')
+				buf = strconv.AppendInt(buf, int64(v), 10)
+			}
+			if hl {
+				if color {
+					buf = append(buf, ' ')
+				}
+				buf = append(buf, "hl"...)
+			}
+			buf = append(buf, '"')
+		}
+		if tooltip {
+			buf = append(buf, ` title="offset 0x`...)
+			buf = strconv.AppendInt(buf, off, 16)
+			buf = append(buf, ", dec "...)
+			buf = strconv.AppendInt(buf, int64(v), 10)
+			buf = append(buf, '"')
+		}
+		return append(buf, '>')
+	}
+
+	err := d.rows(r, func(row *dumpRow) error {
+		if row.data == nil {
+			_, err := w.Write([]byte("*\n"))
+			return err
+		}
+
+		anchor := "0x" + strconv.FormatInt(row.offset, 16)
+		buf = append(buf[:0], `<span class="row" id="`...)
+		buf = append(buf, anchor...)
+		buf = append(buf, `"><a class="off" href="#`...)
+		buf = append(buf, anchor...)
+		buf = append(buf, `">`...)
+		buf = d.appendPadded(buf, row.offset, 7)
+		buf = append(buf, ":</a> "...)
+
+		for i := 0; i < d.cols; i++ {
+			if i < len(row.data) {
+				buf = openSpan(buf, row.data[i], row.offset+int64(i), true)
+				buf = append(buf, d.tables.hex[row.data[i]][:]...)
+				buf = append(buf, "</span>"...)
+			} else {
+				buf = append(buf, "  "...)
+			}
+			if d.groupSize > 0 && (i+1)%d.groupSize == 0 {
+				buf = append(buf, ' ')
+			}
+		}
+		buf = append(buf, ' ')
+
+		if opts.Bars {
+			buf = append(buf, `<span class="g">`...)
+			buf = append(buf, sep...)
+			buf = append(buf, "</span>"...)
+		}
+
+		// every cell of the text column belongs to a byte, a wide glyph to two of them
+		i := 0
+		for _, c := range row.text {
+			v := row.data[min(i, len(row.data)-1)]
+			if c == '·' || c == '.' && v != '.' {
+				buf = append(buf, `<span class="g">`...)
+				buf = utf8.AppendRune(buf, c)
+				buf = append(buf, "</span>"...)
+			} else {
+				buf = openSpan(buf, v, row.offset+int64(i), false)
+				buf = append(buf, html.EscapeString(string(c))...)
+				buf = append(buf, "</span>"...)
+			}
+			i++
+			if isWide(c) {
+				i++
+			}
+		}
+
+		if opts.Bars {
+			buf = append(buf, `<span class="g">`...)
+			buf = append(buf, sep...)
+			buf = append(buf, "</span>"...)
+		}
+
+		buf = append(buf, "</span>\n"...)
+		_, err := w.Write(buf)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "</pre>\n</body>\n</html>\n")
+	return err
+}
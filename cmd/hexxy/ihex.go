@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Intel HEX record types
+const (
+	ihexData         = 0x00
+	ihexEOF          = 0x01
+	ihexExtSegment   = 0x02
+	ihexStartSegment = 0x03
+	ihexExtLinear    = 0x04
+	ihexStartLinear  = 0x05
+)
+
+var ErrChecksum = errors.New("bad record checksum")
+
+// a contiguous run of bytes at an absolute address, used when rebuilding
+// a binary image from address based formats (ihex, srec)
+type segment struct {
+	addr uint32
+	data []byte
+}
+
+// parses numbers given on the command line like "0x08000000", "0o17" or "4096"
+func parseNumber(s string, bits int) (uint64, error) {
+	v, err := strconv.ParseUint(s, 0, bits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q: %v", s, err)
+	}
+	return v, nil
+}
+
+// returns the --base-addr value and whether it was set at all
+func baseAddress() (uint32, bool, error) {
+	if opts.BaseAddr == "" {
+		return 0, false, nil
+	}
+	v, err := parseNumber(opts.BaseAddr, 32)
+	return uint32(v), true, err
+}
+
+// writes a single record in the form of :LLAAAATTDD..CC
+// the checksum is the two's complement of the sum of every byte in the record
+func writeIntelRecord(w io.Writer, buf []byte, typ byte, addr uint16, data []byte) []byte {
+	sum := byte(len(data)) + byte(addr>>8) + byte(addr) + typ
+
+	buf = append(buf[:0], ':')
+	buf = appendHexByte(buf, byte(len(data)))
+	buf = appendHexByte(buf, byte(addr>>8))
+	buf = appendHexByte(buf, byte(addr))
+	buf = appendHexByte(buf, typ)
+	for _, b := range data {
+		buf = appendHexByte(buf, b)
+		sum += b
+	}
+	buf = appendHexByte(buf, -sum)
+	buf = append(buf, '\n')
+
+	w.Write(buf)
+	return buf
+}
+
+// Intel HEX is conventionally written in uppercase, so we ignore --upper here
+func appendHexByte(dst []byte, b byte) []byte {
+	return append(dst, udigits[b>>4], udigits[b&0x0f])
+}
+
+// IntelHexDump writes r as Intel HEX records starting at --base-addr
+// data records never cross a 64KiB boundary, an extended linear address record
+// is written every time the upper 16 bits of the address change
+func IntelHexDump(r io.Reader, w io.Writer) error {
+	base, _, err := baseAddress()
+	if err != nil {
+		return err
+	}
+
+	cols := 16
+	if opts.Columns != -1 {
+		cols = opts.Columns
+	}
+	if cols < 1 || cols > 255 {
+		return fmt.Errorf("ihex: record length must be between 1 and 255, got %d", cols)
+	}
+
+	var (
+		addr  = uint64(base)
+		upper = uint64(0)
+		line  = make([]byte, cols)
+		rec   = make([]byte, 0, 12+cols*2)
+		ext   = make([]byte, 2)
+	)
+
+	r = bufio.NewReader(r)
+
+	for {
+		n := cols
+		if lower := addr & 0xffff; lower+uint64(n) > 0x10000 {
+			n = int(0x10000 - lower)
+		}
+
+		n, err = io.ReadFull(r, line[:n])
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+
+		if addr+uint64(n) > 1<<32 {
+			return fmt.Errorf("ihex: input does not fit in a 32-bit address space")
+		}
+
+		if addr>>16 != upper {
+			upper = addr >> 16
+			ext[0], ext[1] = byte(upper>>8), byte(upper)
+			rec = writeIntelRecord(w, rec, ihexExtLinear, 0, ext)
+		}
+
+		rec = writeIntelRecord(w, rec, ihexData, uint16(addr), line[:n])
+		addr += uint64(n)
+	}
+
+	if opts.EntryAddr != "" {
+		entry, err := parseNumber(opts.EntryAddr, 32)
+		if err != nil {
+			return err
+		}
+		e := []byte{byte(entry >> 24), byte(entry >> 16), byte(entry >> 8), byte(entry)}
+		rec = writeIntelRecord(w, rec, ihexStartLinear, 0, e)
+	}
+
+	writeIntelRecord(w, rec, ihexEOF, 0, nil)
+	return nil
+}
+
+// IntelHexReverse parses Intel HEX records and writes the raw binary image to w
+// checksums are verified and gaps between records are filled with --fill
+func IntelHexReverse(r io.Reader, w io.Writer) error {
+	var (
+		segs   []segment
+		upper  uint32
+		lineNo int
+		rec    []byte
+	)
+
+	rd := bufio.NewReader(r)
+	for {
+		line, err := rd.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		lineNo++
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			if err != nil {
+				return fmt.Errorf("ihex: missing end of file record")
+			}
+			continue
+		}
+
+		if line[0] != ':' {
+			return fmt.Errorf("ihex: line %d: record does not start with ':'", lineNo)
+		}
+
+		rec = append(rec[:0], make([]byte, (len(line)-1)/2)...)
+		if _, derr := hexDecode(rec, line[1:]); derr != nil {
+			return fmt.Errorf("ihex: line %d: %v", lineNo, derr)
+		}
+
+		if len(rec) < 5 || int(rec[0]) != len(rec)-5 {
+			return fmt.Errorf("ihex: line %d: bad record length", lineNo)
+		}
+
+		var sum byte
+		for _, b := range rec {
+			sum += b
+		}
+		if sum != 0 {
+			return fmt.Errorf("ihex: line %d: %w", lineNo, ErrChecksum)
+		}
+
+		addr := uint32(rec[1])<<8 | uint32(rec[2])
+		data := rec[4 : len(rec)-1]
+
+		switch rec[3] {
+		case ihexData:
+			segs = append(segs, segment{addr: upper + addr, data: append([]byte(nil), data...)})
+		case ihexEOF:
+			return writeImage(w, segs, opts.Fill)
+		case ihexExtSegment:
+			if len(data) != 2 {
+				return fmt.Errorf("ihex: line %d: bad extended segment address", lineNo)
+			}
+			upper = (uint32(data[0])<<8 | uint32(data[1])) << 4
+		case ihexExtLinear:
+			if len(data) != 2 {
+				return fmt.Errorf("ihex: line %d: bad extended linear address", lineNo)
+			}
+			upper = (uint32(data[0])<<8 | uint32(data[1])) << 16
+		case ihexStartSegment, ihexStartLinear:
+			Debug("ihex: start address %X\n", data)
+		default:
+			return fmt.Errorf("ihex: line %d: unknown record type %02X", lineNo, rec[3])
+		}
+
+		if err != nil {
+			return fmt.Errorf("ihex: missing end of file record")
+		}
+	}
+}
+
+// writes segments as one contiguous image, gaps are filled with the --fill byte
+// the image starts at --base-addr or at the lowest address found in the input
+func writeImage(w io.Writer, segs []segment, fillByte string) error {
+	if len(segs) == 0 {
+		return nil
+	}
+
+	fill, err := parseNumber(fillByte, 8)
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(segs, func(i, j int) bool { return segs[i].addr < segs[j].addr })
+
+	start, ok, err := baseAddress()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		start = segs[0].addr
+	}
+
+	var (
+		pos = uint64(start)
+		pad = bytes.Repeat([]byte{byte(fill)}, 4096)
+	)
+
+	for _, s := range segs {
+		addr, data := uint64(s.addr), s.data
+		end := addr + uint64(len(data))
+
+		if end <= pos {
+			continue // before the base address or fully overlapped
+		}
+		if addr < pos {
+			data = data[pos-addr:]
+			addr = pos
+		}
+
+		for gap := addr - pos; gap > 0; {
+			n := min(gap, uint64(len(pad)))
+			if _, err := w.Write(pad[:n]); err != nil {
+				return err
+			}
+			gap -= n
+		}
+
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		pos = end
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// sets opts as the command line would, the flags are restored when the test ends
+func setFlags(t *testing.T, args ...string) {
+	saved := opts
+	t.Cleanup(func() { opts = saved })
+	if _, err := flags.NewParser(&opts, flags.None).ParseArgs(args); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// random bytes with runs of zeros, long enough to cross a 64KiB boundary
+func roundTripInput() []byte {
+	rnd := rand.New(rand.NewSource(3))
+	b := make([]byte, 70001)
+	rnd.Read(b)
+	clear(b[100:300])
+	clear(b[len(b)-50:])
+	return b
+}
+
+func TestIntelHexRoundTrip(t *testing.T) {
+	in := roundTripInput()
+	for _, args := range [][]string{
+		nil,
+		{"-c", "7"},
+		{"-c", "255"},
+		{"--base-addr", "0x1fff0"},
+		{"--entry", "0x08000000"},
+	} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			setFlags(t, args...)
+
+			for _, data := range [][]byte{in, in[:1], nil} {
+				var hex, out bytes.Buffer
+				if err := IntelHexDump(bytes.NewReader(data), &hex); err != nil {
+					t.Fatal(err)
+				}
+				if err := IntelHexReverse(&hex, &out); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(out.Bytes(), data) {
+					t.Errorf("%d bytes: the image differs at byte %d", len(data), firstDiff(out.Bytes(), data))
+				}
+			}
+		})
+	}
+}
+
+func TestIntelHexReverse(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		in   string
+		want string // the image, or the error
+	}{
+		{"gap", nil, ":02000000AABB99\n:02000400CCDD51\n:00000001FF\n", "\xaa\xbb\xff\xff\xcc\xdd"},
+		{"fill", []string{"--fill", "0"}, ":02000000AABB99\n:02000400CCDD51\n:00000001FF\n", "\xaa\xbb\x00\x00\xcc\xdd"},
+		{"unordered", nil, ":02000400CCDD51\n:02000000AABB99\n:00000001FF\n", "\xaa\xbb\xff\xff\xcc\xdd"},
+		{"base address", []string{"--base-addr", "2"}, ":02000000AABB99\n:02000400CCDD51\n:00000001FF\n", "\xff\xff\xcc\xdd"},
+		{"extended linear", nil, ":020000040001F9\n:020010000102EB\n:00000001FF\n", "\x01\x02"},
+		{"extended segment", nil, ":020000021000EC\n:02000000AABB99\n:00000001FF\n", "\xaa\xbb"},
+		{"crlf and blank lines", nil, ":02000000AABB99\r\n\r\n:00000001FF\r\n", "\xaa\xbb"},
+		{"checksum", nil, ":02000000AABB98\n:00000001FF\n", "ihex: line 1: bad record checksum"},
+		{"no colon", nil, "02000000AABB99\n:00000001FF\n", "ihex: line 1: record does not start with ':'"},
+		{"not hex", nil, ":0200000GAABB99\n:00000001FF\n", "ihex: line 1:"},
+		{"length", nil, ":03000000AABB98\n:00000001FF\n", "ihex: line 1: bad record length"},
+		{"short", nil, ":0000\n", "ihex: line 1: bad record length"},
+		{"record type", nil, ":0100000701F7\n", "ihex: line 1: unknown record type 07"},
+		{"no end", nil, ":02000000AABB99\n", "ihex: missing end of file record"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlags(t, tt.args...)
+
+			var out bytes.Buffer
+			err := IntelHexReverse(strings.NewReader(tt.in), &out)
+			switch {
+			case err != nil && !strings.HasPrefix(err.Error(), tt.want):
+				t.Errorf("%v, want %q", err, tt.want)
+			case err == nil && out.String() != tt.want:
+				t.Errorf("%q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+
+	if err := IntelHexReverse(strings.NewReader(":02000000AABB98\n"), &bytes.Buffer{}); !errors.Is(err, ErrChecksum) {
+		t.Errorf("a bad checksum is %v, not ErrChecksum", err)
+	}
+}
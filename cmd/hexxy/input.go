@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// a regular file mapped into memory, the hex dump renders the mapped bytes directly
+// and every other dump reads them through the bytes.Reader
+type mappedFile struct {
+	*bytes.Reader
+	data  []byte
+	holes []hole // the parts of a sparse file without data, relative to data
+}
+
+// the input of a dump after --seek and --len, regular files are mapped read-only
+// or read at any offset, pipes and special files are streamed
+// the returned function releases the mapping
+func dumpInput(f *os.File) (io.Reader, func() error, error) {
+	release := func() error { return nil }
+
+	// files in /proc and /sys report a size of 0 and have to be read to find their end
+	st, err := f.Stat()
+	if err != nil || !st.Mode().IsRegular() || st.Size() == 0 {
+		// --seek already moved past the start
+		if opts.Len != -1 {
+			return io.LimitReader(f, opts.Len), release, nil
+		}
+		return f, release, nil
+	}
+
+	pos, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, nil, err
+	}
+	pos = min(pos, st.Size())
+	size := st.Size() - pos
+	if opts.Len != -1 {
+		size = min(size, opts.Len)
+	}
+
+	// mappings start on a page boundary
+	page := pos &^ int64(os.Getpagesize()-1)
+	if m, err := mapFile(f, page, pos-page+size); err == nil {
+		data := m[pos-page:]
+		mf := &mappedFile{bytes.NewReader(data), data, findHoles(f, pos, size)}
+		return mf, func() error { return unmapFile(m) }, nil
+	}
+
+	// not every system can map files
+	return io.NewSectionReader(f, pos, size), release, nil
+}
+
+// expands the glob patterns among args, for shells that don't do it themselves
+// a name without glob characters is kept as it is, even if the file does not exist
+func expandGlobs(args []string) ([]string, error) {
+	var names []string
+	for _, arg := range args {
+		if !strings.ContainsAny(arg, "*?[") {
+			names = append(names, arg)
+			continue
+		}
+
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s: no matching files", arg)
+		}
+		names = append(names, matches...)
+	}
+	return names, nil
+}
+
+// separates the files of a dump with more than one input
+// the hex and binary dumps get a line with the file name, C include output a blank line
+func fileHeader(w io.Writer, name string, first bool) error {
+	var b []byte
+	if !first && dumpType <= dumpCformat {
+		b = append(b, newLine...)
+	}
+	if dumpType <= dumpBinary {
+		b = append(b, fileStart...)
+		b = append(b, name...)
+		b = append(b, fileEnd...)
+	}
+	_, err := w.Write(b)
+	return err
+}
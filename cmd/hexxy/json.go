@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+)
+
+// a single dump row, or a run of zero rows collapsed by --autoskip
+type jsonRow struct {
+	Offset    int64         `json:"offset"`
+	Hex       string        `json:"hex,omitempty"`
+	Bytes     []int         `json:"bytes,omitempty"`
+	ASCII     *string       `json:"ascii,omitempty"`
+	Collapsed *jsonCollapse `json:"collapsed,omitempty"`
+}
+
+type jsonCollapse struct {
+	Rows   int64 `json:"rows"`
+	Length int64 `json:"length"`
+}
+
+// JSONDump writes r as structured rows, either as a single json document with a "rows" array
+// or one object per line for --format ndjson
+// offsets are absolute file offsets, so they include --seek
+func JSONDump(d *dumper, r io.Reader, w io.Writer, filename string) error {
+	var (
+		ndjson = opts.Format == "ndjson"
+		hex    []byte
+		rows   int
+	)
+
+	writeRow := func(row *jsonRow) error {
+		b, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+
+		if !ndjson {
+			if rows > 0 {
+				w.Write([]byte(",\n    "))
+			} else {
+				w.Write([]byte("\n    "))
+			}
+		}
+		w.Write(b)
+		if ndjson {
+			w.Write(newLine)
+		}
+		rows++
+		return nil
+	}
+
+	if !ndjson {
+		name, _ := json.Marshal(path.Base(filename))
+		fmt.Fprintf(w, "{\n  \"file\": %s,\n  \"columns\": %d,\n  \"rows\": [", name, d.cols)
+	}
+
+	err := d.rows(r, func(row *dumpRow) error {
+		if row.data == nil {
+			cols := int64(d.cols)
+			return writeRow(&jsonRow{Offset: row.offset, Collapsed: &jsonCollapse{Rows: (row.skipped + cols - 1) / cols, Length: row.skipped}})
+		}
+
+		hex = hex[:0]
+		values := make([]int, len(row.data))
+		for i, v := range row.data {
+			hex = append(hex, d.tables.hex[v][:]...)
+			values[i] = int(v)
+		}
+		return writeRow(&jsonRow{Offset: row.offset, Hex: string(hex), Bytes: values, ASCII: &row.text})
+	})
+	if err != nil {
+		return err
+	}
+
+	if !ndjson {
+		if rows > 0 {
+			w.Write([]byte("\n  "))
+		}
+		w.Write([]byte("]\n}\n"))
+	}
+
+	return nil
+}
+
+// JSONReverse reads the output of --format json or ndjson and writes the original bytes
+// rows are written in order, collapsed runs are expanded back into zeros
+func JSONReverse(r io.Reader, w io.Writer) error {
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	for {
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("json: %v", err)
+		}
+
+		var rows []jsonRow
+		switch firstByte(v) {
+		case '[':
+			if err := json.Unmarshal(v, &rows); err != nil {
+				return fmt.Errorf("json: %v", err)
+			}
+		case '{':
+			var doc struct {
+				jsonRow
+				Rows []jsonRow `json:"rows"`
+			}
+			if err := json.Unmarshal(v, &doc); err != nil {
+				return fmt.Errorf("json: %v", err)
+			}
+			if doc.Rows != nil {
+				rows = doc.Rows
+			} else {
+				rows = []jsonRow{doc.jsonRow}
+			}
+		default:
+			return fmt.Errorf("json: expected an object or an array of rows")
+		}
+
+		for _, row := range rows {
+			if err := writeJSONRow(w, &row); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeJSONRow(w io.Writer, row *jsonRow) error {
+	switch {
+	case row.Collapsed != nil:
+		// only rows of zeros are collapsed
+		if row.Collapsed.Length < 0 {
+			return fmt.Errorf("json: offset %d: invalid collapsed length %d", row.Offset, row.Collapsed.Length)
+		}
+		pad := make([]byte, 4096)
+		for n := row.Collapsed.Length; n > 0; n -= int64(len(pad)) {
+			if _, err := w.Write(pad[:min(n, int64(len(pad)))]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case row.Bytes != nil:
+		buf := make([]byte, len(row.Bytes))
+		for i, v := range row.Bytes {
+			if v < 0 || v > 0xff {
+				return fmt.Errorf("json: offset %d: %d is not a byte", row.Offset, v)
+			}
+			buf[i] = byte(v)
+		}
+		_, err := w.Write(buf)
+		return err
+	default:
+		buf := make([]byte, len(row.Hex)/2)
+		if _, err := hexDecode(buf, []byte(row.Hex)); err != nil {
+			return fmt.Errorf("json: offset %d: %v", row.Offset, err)
+		}
+		_, err := w.Write(buf)
+		return err
+	}
+}
+
+// first non whitespace byte of a json value
+func firstByte(b []byte) byte {
+	for _, c := range b {
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			continue
+		}
+		return c
+	}
+	return 0
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	in := roundTripInput()
+	for _, format := range []string{"json", "ndjson"} {
+		for _, args := range [][]string{nil, {"-a"}, {"-a", "-c", "7"}, {"-a", "--text", "utf16le"}} {
+			t.Run(format+"/"+strings.Join(args, " "), func(t *testing.T) {
+				setFlags(t, append([]string{"--format", format}, args...)...)
+
+				for _, data := range [][]byte{in, in[:1], nil} {
+					// the zeros at 100 are also a hole of a sparse file, which is always mapped
+					for _, holes := range [][]hole{nil, {{120, 290}}} {
+						if holes != nil && len(data) < 290 {
+							continue
+						}
+
+						d := newBenchDumper(t, dumpJSON, false)
+						var r io.Reader = bytes.NewReader(data)
+						if holes != nil {
+							d.setHoles(holes)
+							r = &mappedFile{bytes.NewReader(data), data, holes}
+						}
+
+						var text, out bytes.Buffer
+						if err := JSONDump(d, r, &text, "in.bin"); err != nil {
+							t.Fatal(err)
+						}
+						if err := JSONReverse(&text, &out); err != nil {
+							t.Fatalf("%d bytes: %v", len(data), err)
+						}
+						if !bytes.Equal(out.Bytes(), data) {
+							t.Errorf("%d bytes, holes %v: the output differs at byte %d", len(data), holes, firstDiff(out.Bytes(), data))
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestJSONReverse(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string // the output, or the error
+	}{
+		{"document", `{"file": "a", "rows": [{"offset": 0, "hex": "4142"}, {"offset": 2, "collapsed": {"rows": 1, "length": 3}}, {"offset": 5, "bytes": [67]}]}`, "AB\x00\x00\x00C"},
+		{"ndjson", "{\"offset\": 0, \"hex\": \"41\"}\n{\"offset\": 1, \"bytes\": [66, 255]}\n", "AB\xff"},
+		{"array", `[{"offset": 0, "hex": "41"}, {"offset": 1, "hex": "42"}]`, "AB"},
+		// the bytes win over the hex
+		{"bytes and hex", `{"offset": 0, "hex": "41", "bytes": [66]}`, "B"},
+		{"empty", `{"file": "a", "columns": 16, "rows": []}`, ""},
+		{"long collapse", `{"offset": 0, "collapsed": {"rows": 1000, "length": 10000}}`, strings.Repeat("\x00", 10000)},
+		{"negative length", `{"offset": 32, "collapsed": {"rows": 1, "length": -1}}`, "json: offset 32: invalid collapsed length -1"},
+		{"not a byte", `{"offset": 16, "bytes": [65, 256]}`, "json: offset 16: 256 is not a byte"},
+		{"negative byte", `{"offset": 16, "bytes": [-1]}`, "json: offset 16: -1 is not a byte"},
+		{"not hex", `{"offset": 16, "hex": "4g"}`, "json: offset 16: encoding/hex: invalid byte: U+0067 'g'"},
+		{"odd hex", `{"offset": 16, "hex": "414"}`, "json: offset 16: encoding/hex: odd length hex string"},
+		{"not a row", `42`, "json: expected an object or an array of rows"},
+		{"truncated", `{"offset": 0, "hex": "41"`, "json: unexpected EOF"},
+		{"wrong type", `{"offset": "0", "hex": "41"}`, "json: json: cannot unmarshal string into Go struct field .offset of type int64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := JSONReverse(strings.NewReader(tt.in), &out)
+			switch {
+			case err != nil && err.Error() != tt.want:
+				t.Errorf("%v, want %q", err, tt.want)
+			case err == nil && out.String() != tt.want:
+				t.Errorf("%q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
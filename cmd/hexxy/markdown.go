@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// a note attached to a range of bytes
+type annotation struct {
+	byteRange
+	text string
+}
+
+// parses annotations in the form of START-END:TEXT or START+LEN:TEXT
+func parseAnnotation(s string) (annotation, error) {
+	rng, text, ok := strings.Cut(s, ":")
+	if !ok {
+		return annotation{}, fmt.Errorf("invalid annotation %q, expected RANGE:TEXT", s)
+	}
+
+	br, err := parseRange(rng)
+	if err != nil {
+		return annotation{}, err
+	}
+	return annotation{byteRange: br, text: strings.TrimSpace(text)}, nil
+}
+
+// joins the text of every annotation that overlaps [start, end)
+func annotationsFor(notes []annotation, start, end int64) string {
+	var s []string
+	for _, a := range notes {
+		if a.start < end && a.end > start {
+			s = append(s, a.text)
+		}
+	}
+	return strings.Join(s, "; ")
+}
+
+// backslash escapes everything markdown could interpret inside a table cell
+func markdownEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '`', '*', '_', '{', '}', '[', ']', '<', '>', '(', ')', '#', '+', '-', '!', '|', '~':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// MarkdownDump writes r as a markdown table or a fenced code block without any escape codes
+// the header shows the column index of every byte and --annotate notes go in a trailing column
+func MarkdownDump(d *dumper, r io.Reader, w io.Writer) error {
+	var notes []annotation
+	for _, s := range opts.Annotate {
+		a, err := parseAnnotation(s)
+		if err != nil {
+			return err
+		}
+		notes = append(notes, a)
+	}
+
+	groupSize := 1
+	if opts.GroupSize != -1 {
+		groupSize = opts.GroupSize
+	}
+
+	var (
+		cols  = d.cols
+		table = opts.MDStyle != "code"
+		buf   []byte
+	)
+
+	// column indices are always in hex, like the byte values below them
+	index := func(i int) []byte {
+		return d.tables.hex[byte(i)][:]
+	}
+
+	if table {
+		buf = append(buf[:0], "| Offset   |"...)
+		for i := 0; i < cols; i++ {
+			buf = append(buf, ' ')
+			buf = append(buf, index(i)...)
+			buf = append(buf, " |"...)
+		}
+		buf = append(buf, " ASCII"...)
+		buf = append(buf, strings.Repeat(" ", max(cols-5, 0))...)
+		buf = append(buf, " |"...)
+		if len(notes) > 0 {
+			buf = append(buf, " Notes |"...)
+		}
+		buf = append(buf, "\n|:---------|"...)
+		for i := 0; i < cols; i++ {
+			buf = append(buf, ":--:|"...)
+		}
+		buf = append(buf, ':')
+		buf = append(buf, strings.Repeat("-", max(cols, 5))...)
+		buf = append(buf, "-|"...)
+		if len(notes) > 0 {
+			buf = append(buf, ":------|"...)
+		}
+		buf = append(buf, '\n')
+	} else {
+		buf = append(buf[:0], "```text\nOffset    "...)
+		for i := 0; i < cols; i++ {
+			buf = append(buf, index(i)...)
+			if groupSize > 0 && (i+1)%groupSize == 0 {
+				buf = append(buf, ' ')
+			}
+		}
+		buf = append(buf, " ASCII\n"...)
+	}
+	w.Write(buf)
+
+	err := d.rows(r, func(row *dumpRow) error {
+		b, n := row.data, len(row.data)
+		if b == nil {
+			if table {
+				buf = append(buf[:0], "| \\*       |"...)
+				buf = append(buf, strings.Repeat("    |", cols)...)
+				buf = append(buf, strings.Repeat(" ", max(cols, 5)+2)...)
+				buf = append(buf, '|')
+				if len(notes) > 0 {
+					buf = append(buf, "       |"...)
+				}
+				buf = append(buf, '\n')
+			} else {
+				buf = append(buf[:0], "*\n"...)
+			}
+			_, err := w.Write(buf)
+			return err
+		}
+
+		note := annotationsFor(notes, row.offset, row.offset+int64(n))
+
+		// offsets are padded to 8 digits so the header lines up
+		if table {
+			buf = append(buf[:0], "| "...)
+			buf = d.appendPadded(buf, row.offset, 8)
+			buf = append(buf, " |"...)
+			for i := 0; i < cols; i++ {
+				if i < n {
+					buf = append(buf, ' ')
+					buf = append(buf, d.tables.hex[b[i]][:]...)
+					buf = append(buf, " |"...)
+				} else {
+					buf = append(buf, "    |"...)
+				}
+			}
+			buf = append(buf, ' ')
+			buf = append(buf, markdownEscape(row.text)...)
+			buf = append(buf, strings.Repeat(" ", max(max(cols, 5)-n, 0))...)
+			buf = append(buf, " |"...)
+			if len(notes) > 0 {
+				buf = append(buf, ' ')
+				buf = append(buf, markdownEscape(note)...)
+				buf = append(buf, " |"...)
+			}
+		} else {
+			buf = d.appendPadded(buf[:0], row.offset, 8)
+			buf = append(buf, "  "...)
+			for i := 0; i < cols; i++ {
+				if i < n {
+					buf = append(buf, d.tables.hex[b[i]][:]...)
+				} else {
+					buf = append(buf, "  "...)
+				}
+				if groupSize > 0 && (i+1)%groupSize == 0 {
+					buf = append(buf, ' ')
+				}
+			}
+			buf = append(buf, ' ')
+			buf = append(buf, row.text...)
+			if note != "" {
+				buf = append(buf, strings.Repeat(" ", cols-n+2)...)
+				buf = append(buf, "; "...)
+				buf = append(buf, note...)
+			}
+		}
+
+		buf = append(buf, '\n')
+		_, err := w.Write(buf)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if !table {
+		w.Write([]byte("```\n"))
+	}
+	return nil
+}
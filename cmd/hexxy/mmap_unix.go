@@ -0,0 +1,21 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// maps length bytes of f starting at offset, offset has to be a multiple of the page size
+func mapFile(f *os.File, offset, length int64) ([]byte, error) {
+	if length <= 0 || length != int64(int(length)) {
+		return nil, errors.ErrUnsupported
+	}
+	return syscall.Mmap(int(f.Fd()), offset, int(length), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func unmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
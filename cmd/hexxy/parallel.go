@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// the amount of input every worker renders at once
+const chunkSize = 1 << 20
+
+// dumps r with a pool of workers that each render a chunk of rows,
+// the chunks are written in order so the output matches stream
+func (d *dumper) parallel(r io.ReaderAt, size int64, w io.Writer, jobs int) error {
+	type result struct {
+		buf []byte
+		err error
+	}
+
+	var (
+		rows = max(chunkSize/d.cols, 2)
+		done = make(chan struct{})
+		// a chunk for every worker is kept in flight, in the order it has to be written
+		pending = make(chan chan result, jobs)
+		workers = make(chan struct{}, jobs)
+	)
+
+	// chunks start on an even offset so utf-16 stays aligned
+	rows += rows % 2
+	step := int64(rows * d.cols)
+
+	// the workers copy d, so the header is written before they start
+	d.header()
+	if _, err := w.Write(d.cw.flush()); err != nil {
+		return err
+	}
+
+	go func() {
+		defer close(pending)
+		for off := int64(0); off < size; off += step {
+			ch := make(chan result, 1)
+			select {
+			case pending <- ch:
+			case <-done:
+				return
+			}
+
+			workers <- struct{}{}
+			go func(off int64) {
+				buf, err := d.chunk(r, off, min(step, size-off))
+				<-workers
+				ch <- result{buf, err}
+			}(off)
+		}
+	}()
+	defer close(done)
+
+	for ch := range pending {
+		res := <-ch
+		if res.err != nil {
+			return res.err
+		}
+		if _, err := w.Write(res.buf); err != nil {
+			return err
+		}
+	}
+
+	d.footer(size)
+	_, err := w.Write(d.cw.flush())
+	return err
+}
+
+// renders the n bytes at off as if every row before it had been dumped
+func (d *dumper) chunk(r io.ReaderAt, off, n int64) ([]byte, error) {
+	c := d.at(off)
+	if c.hole < len(c.holes) && c.holes[c.hole].start < off && c.holes[c.hole].end >= off+n {
+		// the chunk is in the middle of a hole
+		return nil, nil
+	}
+
+	// the two rows before the chunk decide the autoskip state
+	// and the last bytes before it whether a glyph continues into it
+	back := min(off, int64(2*d.cols))
+
+	var buf []byte
+	if m, ok := r.(*mappedFile); ok {
+		// mapped files are rendered in place
+		buf = m.data[off-back : min(off+n+utf8.UTFMax, int64(len(m.data)))]
+	} else {
+		buf = make([]byte, back+n+utf8.UTFMax)
+		m, err := r.ReadAt(buf, off-back)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("hexxy: %v", err)
+		}
+		if int64(m) < back+n {
+			return nil, fmt.Errorf("hexxy: %v", io.ErrUnexpectedEOF)
+		}
+		buf = buf[:m]
+	}
+
+	prev, data, next := buf[:back], buf[back:back+n], buf[back+n:]
+
+	if dumpType <= dumpBinary {
+		if opts.Autoskip {
+			for i := len(prev); i >= d.cols; i -= d.cols {
+				row := prev[i-d.cols : i]
+				if !isEmpty(&row) {
+					break
+				}
+				c.nulLine++
+			}
+		}
+
+		if c.nulLine < 2 {
+			// decoding 8 bytes back is enough for every text encoding to find its way back,
+			// the tail starts on an even offset like the code units of the whole dump
+			tail := prev[max(len(prev)-8, 0):]
+			c.text.render(&colorWriter{}, tail, data, d.color)
+		} else {
+			// the previous row was skipped, the text starts over like after any skipped row
+			c.text.skip(off)
+		}
+	}
+
+	for i := 0; i < len(data); i += d.cols {
+		if c.skipHole() {
+			i = int(c.offset-off) - d.cols
+			continue
+		}
+
+		end := min(i+d.cols, len(data))
+		after := data[end:min(end+utf8.UTFMax, len(data))]
+		if end == len(data) {
+			after = next
+		}
+		c.row(data[i:end], after)
+	}
+
+	return c.cw.buf, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"unicode/utf16"
+)
+
+// a few chunks of utf-8 and utf-16 text, random bytes and runs of zeros of every length,
+// with a long run of zeros in the middle of the second chunk for a hole
+func parallelInput() ([]byte, hole) {
+	rnd := rand.New(rand.NewSource(2))
+
+	var units []byte
+	for _, u := range utf16.Encode([]rune("héllo wörld 日本語 😀 ")) {
+		units = append(units, byte(u), byte(u>>8))
+	}
+
+	const size = 3*chunkSize + 12345
+	var b []byte
+	for len(b) < size {
+		switch rnd.Intn(4) {
+		case 0:
+			b = append(b, units...)
+		case 1:
+			b = append(b, "plain text, 日本語 and 😀 "...)
+		case 2:
+			run := make([]byte, rnd.Intn(24))
+			rnd.Read(run)
+			b = append(b, run...)
+		default:
+			b = append(b, make([]byte, rnd.Intn(80))...)
+		}
+	}
+	b = b[:size]
+
+	h := hole{chunkSize + 1001, chunkSize + 70001}
+	clear(b[h.start:h.end])
+	return b, h
+}
+
+// --jobs must give the same output as the sequential dump, also across chunk boundaries
+func TestParallelMatchesSequential(t *testing.T) {
+	in, h := parallelInput()
+	saved := opts
+	t.Cleanup(func() { opts = saved })
+
+	for _, typ := range []int{dumpHex, dumpBinary} {
+		for _, cols := range []int{3, 16} {
+			for _, text := range []string{"utf8", "utf16le"} {
+				for _, autoskip := range []bool{false, true} {
+					opts.Columns, opts.Text, opts.Autoskip = cols, text, autoskip
+
+					d := newBenchDumper(t, typ, false)
+					d.setHoles([]hole{h})
+					var want bytes.Buffer
+					if err := d.slice(in, &want); err != nil {
+						t.Fatal(err)
+					}
+
+					for _, r := range []interface {
+						ReadAt([]byte, int64) (int, error)
+					}{bytes.NewReader(in), &mappedFile{bytes.NewReader(in), in, nil}} {
+						d := newBenchDumper(t, typ, false)
+						d.setHoles([]hole{h})
+						var got bytes.Buffer
+						if err := d.parallel(r, int64(len(in)), &got, 4); err != nil {
+							t.Fatal(err)
+						}
+
+						if !bytes.Equal(got.Bytes(), want.Bytes()) {
+							t.Errorf("type %d, -c %d, --text %s, -a %v, %T: the output differs from the sequential dump at byte %d",
+								typ, cols, text, autoskip, r, firstDiff(got.Bytes(), want.Bytes()))
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func firstDiff(a, b []byte) int {
+	for i := 0; i < min(len(a), len(b)); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return min(len(a), len(b))
+}
@@ -14,6 +14,21 @@ func HexxyReverse(r io.Reader, w io.Writer) error {
 		char = make([]byte, 1)
 	)
 
+	switch dumpType {
+	case dumpIntelHex:
+		return IntelHexReverse(r, w)
+	case dumpSrec:
+		return SrecReverse(r, w)
+	case dumpEncoded:
+		return EncodeReverse(r, w)
+	case dumpEscaped:
+		return EscapeReverse(r, w)
+	case dumpHDL:
+		return HDLReverse(r, w)
+	case dumpJSON:
+		return JSONReverse(r, w)
+	}
+
 	if opts.Columns != -1 {
 		cols = opts.Columns
 	}
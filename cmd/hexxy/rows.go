@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// a row of the json, csv, html, markdown and template formats
+// runs of empty rows skipped by --autoskip and the holes of a sparse file become a single
+// collapsed row without data
+type dumpRow struct {
+	offset  int64  // absolute offset of the first byte
+	data    []byte // the bytes of the row, only valid until the next row
+	text    string // the text column as --text, --codepage and --glyphs draw it, one cell per byte
+	skipped int64  // bytes collapsed into the row
+}
+
+// reads r row by row like the hex dump and hands every row to fn
+func (d *dumper) rows(r io.Reader, fn func(row *dumpRow) error) error {
+	var (
+		br   *bufio.Reader
+		data []byte // the whole input when it's mapped
+		line = make([]byte, d.cols)
+		row  dumpRow
+		run  dumpRow // the collapsed run in progress
+	)
+
+	if m, ok := r.(*mappedFile); ok {
+		data = m.data
+	} else {
+		br = bufio.NewReader(r)
+	}
+
+	// adds n bytes at offset to the collapsed run, d.offset is already past them
+	skip := func(offset, n int64) {
+		if run.skipped == 0 {
+			run.offset = offset
+		}
+		run.skipped += n
+		d.text.skip(d.offset)
+	}
+
+	// hands out the collapsed run, if there is one
+	flush := func() error {
+		if run.skipped == 0 {
+			return nil
+		}
+		err := fn(&run)
+		run = dumpRow{}
+		return err
+	}
+
+	for {
+		if h, ok := d.nextHole(); ok {
+			from := d.offset
+			d.offset = h.end
+			skip(d.start+from, h.end-from)
+			if opts.Autoskip {
+				d.nulLine = 2
+			}
+			continue
+		}
+
+		var b, next []byte
+		if br == nil {
+			if d.offset >= int64(len(data)) {
+				break
+			}
+			end := min(d.offset+int64(d.cols), int64(len(data)))
+			b, next = data[d.offset:end], data[end:min(end+utf8.UTFMax, int64(len(data)))]
+		} else {
+			n, err := io.ReadFull(br, line)
+			if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+				return fmt.Errorf("hexxy: %v", err)
+			}
+			if n == 0 {
+				break
+			}
+			// multi-byte sequences may continue in the next row
+			b = line[:n]
+			next, _ = br.Peek(utf8.UTFMax)
+		}
+
+		offset := d.start + d.offset
+		d.offset += int64(len(b))
+
+		if opts.Autoskip && isEmpty(&b) {
+			d.nulLine++
+			if d.nulLine > 1 {
+				skip(offset, int64(len(b)))
+				continue
+			}
+		} else {
+			d.nulLine = 0
+		}
+
+		if err := flush(); err != nil {
+			return err
+		}
+
+		d.text.render(&d.cw, b, next, nil)
+		row = dumpRow{offset: offset, data: b, text: string(d.cw.flush())}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+
+	return flush()
+}
+
+// appends offset in the --radix format, padded with zeros to width digits
+func (d *dumper) appendPadded(buf []byte, offset int64, width int) []byte {
+	var digits [64]byte
+	s := strconv.AppendInt(digits[:0], offset, d.colFmt)
+	for i := len(s); i < width; i++ {
+		buf = append(buf, '0')
+	}
+	return append(buf, s...)
+}
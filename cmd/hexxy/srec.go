@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+)
+
+// SrecDump writes r as Motorola S-records starting at --base-addr
+// the address width (S19, S28 or S37) is picked from the highest address
+// unless --srec-type forces one
+func SrecDump(r io.Reader, w io.Writer, filename string) error {
+	base, _, err := baseAddress()
+	if err != nil {
+		return err
+	}
+
+	var entry uint64
+	if opts.EntryAddr != "" {
+		if entry, err = parseNumber(opts.EntryAddr, 32); err != nil {
+			return err
+		}
+	}
+
+	// address size in bytes: 2 = S1/S9, 3 = S2/S8, 4 = S3/S7
+	var alen int
+	switch opts.SrecType {
+	case "19":
+		alen = 2
+	case "28":
+		alen = 3
+	case "37":
+		alen = 4
+	default:
+		var size int64
+		if size, r, err = srecSize(r, base); err != nil {
+			return err
+		}
+
+		// highest address that has to be representable
+		top := entry
+		if size > 0 {
+			top = max(uint64(base)+uint64(size)-1, entry)
+		}
+		switch {
+		case top <= 0xffff:
+			alen = 2
+		case top <= 0xffffff:
+			alen = 3
+		default:
+			alen = 4
+		}
+	}
+
+	if entry >= 1<<(8*alen) {
+		return fmt.Errorf("srec: address %#x does not fit in S%d records", entry, alen-1)
+	}
+
+	cols := 16
+	if opts.Columns != -1 {
+		cols = opts.Columns
+	}
+	if cols < 1 || cols > 254-alen {
+		return fmt.Errorf("srec: record length must be between 1 and %d, got %d", 254-alen, cols)
+	}
+
+	var (
+		rec   = make([]byte, 0, 12+cols*2)
+		line  = make([]byte, cols)
+		count uint64
+		addr  = uint64(base)
+	)
+
+	// the header is cut to what fits in a record
+	header := []byte(path.Base(filename))
+	rec = writeSrecRecord(w, rec, '0', 2, 0, header[:min(len(header), 252)])
+
+	r = bufio.NewReader(r)
+	for {
+		n, err := io.ReadFull(r, line)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+
+		if end := addr + uint64(n); end > 1<<32 {
+			return fmt.Errorf("srec: input does not fit in a 32-bit address space")
+		} else if end > 1<<(8*alen) {
+			return fmt.Errorf("srec: address %#x does not fit in S%d records", end-1, alen-1)
+		}
+
+		rec = writeSrecRecord(w, rec, byte('0'+alen-1), alen, addr, line[:n])
+		addr += uint64(n)
+		count++
+	}
+
+	if count <= 0xffff {
+		rec = writeSrecRecord(w, rec, '5', 2, count, nil)
+	} else if count <= 0xffffff {
+		rec = writeSrecRecord(w, rec, '6', 3, count, nil)
+	}
+
+	writeSrecRecord(w, rec, byte('0'+11-alen), alen, entry, nil)
+	return nil
+}
+
+// the size of the input, which decides the address width
+// an input that can't tell is read ahead until it ends or no longer fits in S28 records,
+// the returned reader starts over with the bytes read ahead
+func srecSize(r io.Reader, base uint32) (int64, io.Reader, error) {
+	if s, ok := r.(interface{ Size() int64 }); ok {
+		return s.Size(), r, nil
+	}
+
+	// at least a byte, to tell an empty input from one above S28 addresses
+	head, err := io.ReadAll(io.LimitReader(r, max(1<<24-int64(base), 0)+1))
+	if err != nil {
+		return 0, nil, err
+	}
+	return int64(len(head)), io.MultiReader(bytes.NewReader(head), r), nil
+}
+
+// writes a single record in the form of STLLAAAA..DD..CC
+// the checksum is the ones' complement of the sum of the count, address and data bytes
+func writeSrecRecord(w io.Writer, buf []byte, typ byte, alen int, addr uint64, data []byte) []byte {
+	count := byte(alen + len(data) + 1)
+	sum := count
+
+	buf = append(buf[:0], 'S', typ)
+	buf = appendHexByte(buf, count)
+	for i := alen - 1; i >= 0; i-- {
+		b := byte(addr >> (8 * i))
+		buf = appendHexByte(buf, b)
+		sum += b
+	}
+	for _, b := range data {
+		buf = appendHexByte(buf, b)
+		sum += b
+	}
+	buf = appendHexByte(buf, ^sum)
+	buf = append(buf, '\n')
+
+	w.Write(buf)
+	return buf
+}
+
+// SrecReverse parses Motorola S-records and writes the raw binary image to w
+// checksums and record counts are verified and gaps are filled with --fill
+func SrecReverse(r io.Reader, w io.Writer) error {
+	var (
+		segs   []segment
+		lineNo int
+		rec    []byte
+		count  uint64
+	)
+
+	rd := bufio.NewReader(r)
+	for {
+		line, err := rd.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		lineNo++
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			if err != nil {
+				// the termination record is optional in practice
+				return writeImage(w, segs, opts.Fill)
+			}
+			continue
+		}
+
+		if len(line) < 4 || line[0] != 'S' {
+			return fmt.Errorf("srec: line %d: record does not start with 'S'", lineNo)
+		}
+
+		typ := line[1]
+		rec = append(rec[:0], make([]byte, (len(line)-2)/2)...)
+		if _, derr := hexDecode(rec, line[2:]); derr != nil {
+			return fmt.Errorf("srec: line %d: %v", lineNo, derr)
+		}
+
+		if int(rec[0]) != len(rec)-1 {
+			return fmt.Errorf("srec: line %d: bad record length", lineNo)
+		}
+
+		var sum byte
+		for _, b := range rec[:len(rec)-1] {
+			sum += b
+		}
+		if ^sum != rec[len(rec)-1] {
+			return fmt.Errorf("srec: line %d: %w", lineNo, ErrChecksum)
+		}
+
+		var alen int
+		switch typ {
+		case '0', '1', '5', '9':
+			alen = 2
+		case '2', '6', '8':
+			alen = 3
+		case '3', '7':
+			alen = 4
+		default:
+			return fmt.Errorf("srec: line %d: unknown record type S%c", lineNo, typ)
+		}
+
+		if len(rec) < alen+2 {
+			return fmt.Errorf("srec: line %d: bad record length", lineNo)
+		}
+
+		var addr uint64
+		for _, b := range rec[1 : 1+alen] {
+			addr = addr<<8 | uint64(b)
+		}
+		data := rec[1+alen : len(rec)-1]
+
+		switch typ {
+		case '0':
+			Debug("srec: header %q\n", data)
+		case '1', '2', '3':
+			segs = append(segs, segment{addr: uint32(addr), data: append([]byte(nil), data...)})
+			count++
+		case '5', '6':
+			if addr != count {
+				return fmt.Errorf("srec: line %d: record count is %d but found %d data records", lineNo, addr, count)
+			}
+		case '7', '8', '9':
+			Debug("srec: start address %#x\n", addr)
+			return writeImage(w, segs, opts.Fill)
+		}
+
+		if err != nil {
+			return writeImage(w, segs, opts.Fill)
+		}
+	}
+}
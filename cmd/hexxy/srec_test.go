@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSrecRoundTrip(t *testing.T) {
+	in := roundTripInput()
+	tests := []struct {
+		args []string
+		typ  string // the data records of in
+	}{
+		{nil, "S2"},
+		{[]string{"-c", "7"}, "S2"},
+		{[]string{"-c", "250"}, "S2"},
+		{[]string{"--srec-type", "37"}, "S3"},
+		{[]string{"--base-addr", "0xfff00000"}, "S3"},
+		{[]string{"--entry", "0x08000000"}, "S3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			setFlags(t, tt.args...)
+
+			for _, data := range [][]byte{in, in[:1], nil} {
+				// a reader without a size is read ahead to pick the address width
+				for _, r := range []io.Reader{bytes.NewReader(data), struct{ io.Reader }{bytes.NewReader(data)}} {
+					var srec, out bytes.Buffer
+					if err := SrecDump(r, &srec, strings.Repeat("long name ", 30)); err != nil {
+						t.Fatal(err)
+					}
+					if len(data) == len(in) && !strings.Contains(srec.String(), "\n"+tt.typ) {
+						t.Errorf("%T: no %s records", r, tt.typ)
+					}
+
+					if err := SrecReverse(&srec, &out); err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(out.Bytes(), data) {
+						t.Errorf("%T, %d bytes: the image differs at byte %d", r, len(data), firstDiff(out.Bytes(), data))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSrecReverse(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		in   string
+		want string // the image, or the error
+	}{
+		{"gap", nil, "S0060000686472BB\nS1050000AABB95\nS1050004CCDD4D\nS5030002FA\nS9030000FC\n", "\xaa\xbb\xff\xff\xcc\xdd"},
+		{"fill", []string{"--fill", "0"}, "S1050000AABB95\nS1050004CCDD4D\nS9030000FC\n", "\xaa\xbb\x00\x00\xcc\xdd"},
+		{"base address", []string{"--base-addr", "4"}, "S1050000AABB95\nS1050004CCDD4D\nS9030000FC\n", "\xcc\xdd"},
+		{"S3", nil, "S307000100000102F4\nS70500000000FA\n", "\x01\x02"},
+		// the termination record is optional
+		{"no end", nil, "S1050000AABB95\r\n", "\xaa\xbb"},
+		{"checksum", nil, "S1050000AABB94\nS9030000FC\n", "srec: line 1: bad record checksum"},
+		{"count", nil, "S1050000AABB95\nS5030003F9\nS9030000FC\n", "srec: line 2: record count is 3 but found 1 data records"},
+		{"no S", nil, "1050000AABB95\n", "srec: line 1: record does not start with 'S'"},
+		{"not hex", nil, "S1050000AABX95\n", "srec: line 1:"},
+		{"length", nil, "S1060000AABB95\n", "srec: line 1: bad record length"},
+		{"short", nil, "S101FE\n", "srec: line 1: bad record length"},
+		{"record type", nil, "S404000001FA\n", "srec: line 1: unknown record type S4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlags(t, tt.args...)
+
+			var out bytes.Buffer
+			err := SrecReverse(strings.NewReader(tt.in), &out)
+			switch {
+			case err != nil && !strings.HasPrefix(err.Error(), tt.want):
+				t.Errorf("%v, want %q", err, tt.want)
+			case err == nil && out.String() != tt.want:
+				t.Errorf("%q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+
+	if err := SrecReverse(strings.NewReader("S1050000AABB94\n"), &bytes.Buffer{}); !errors.Is(err, ErrChecksum) {
+		t.Errorf("a bad checksum is %v, not ErrChecksum", err)
+	}
+}
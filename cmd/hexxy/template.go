@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// the data every row template is executed with
+type templateRow struct {
+	Index  int      // row number starting at 0
+	Offset int64    // absolute offset of the first byte
+	Bytes  []byte   // raw bytes of the row
+	Hex    string   // every byte in hex without separators
+	ASCII  string   // printable characters or '.'
+	Groups []string // hex grouped by --groups
+
+	// bytes of empty rows or a hole collapsed into this row, it has no bytes then
+	Skipped int64
+}
+
+// the data header and footer templates are executed with
+type templateInfo struct {
+	File    string // name of the input
+	Columns int    // bytes per row
+	Rows    int    // rows written, only known in the footer
+	Size    int64  // bytes dumped, only known in the footer
+}
+
+// uses the argument as the template when it has an action, otherwise reads it from the file it names
+// a leading ~/ is the home directory, the ini file passes it on as it is
+func loadTemplate(name, arg string, funcs template.FuncMap) (*template.Template, error) {
+	text := arg
+	if !strings.Contains(arg, "{{") {
+		file := arg
+		if rest, ok := strings.CutPrefix(file, "~/"); ok {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("template: %v", err)
+			}
+			file = filepath.Join(home, rest)
+		}
+
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("template: %v", err)
+		}
+		text = string(b)
+	}
+
+	// a template given inline usually lacks the trailing newline
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+
+	t, err := template.New(name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("template: %v", err)
+	}
+	return t, nil
+}
+
+// helpers available in every template
+func templateFuncs(d *dumper) template.FuncMap {
+	return template.FuncMap{
+		// formats a number in any base: {{radix 2 .Offset}}
+		"radix": func(base int, v any) (string, error) {
+			switch n := v.(type) {
+			case int:
+				return strconv.FormatInt(int64(n), base), nil
+			case int64:
+				return strconv.FormatInt(n, base), nil
+			case byte:
+				return strconv.FormatInt(int64(n), base), nil
+			}
+			return "", fmt.Errorf("radix: %v is not a number", v)
+		},
+		// the offset in the --radix format padded like the hex dump: {{offset .Offset}}
+		"offset": func(off int64) string {
+			return string(d.appendPadded(nil, off, 7))
+		},
+		// a single byte in hex: {{range .Bytes}}{{hex .}} {{end}}
+		"hex": func(b byte) string {
+			return string(d.tables.hex[b][:])
+		},
+		// pads on the left with spaces, or with zeros using zpad
+		"pad": func(width int, s string) string {
+			return strings.Repeat(" ", max(width-len(s), 0)) + s
+		},
+		"rpad": func(width int, s string) string {
+			return s + strings.Repeat(" ", max(width-len(s), 0))
+		},
+		"zpad": func(width int, s string) string {
+			return strings.Repeat("0", max(width-len(s), 0)) + s
+		},
+		// colors text with the color of a byte, a no-op when color is off: {{color . (hex .)}}
+		"color": func(b byte, s string) string {
+			if !USE_COLOR {
+				return s
+			}
+			return d.color.Colorize(s, b)
+		},
+		"grey": func(s string) string {
+			if !USE_COLOR {
+				return s
+			}
+			return string(GREY) + s + string(CLEAR)
+		},
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"join":  strings.Join,
+	}
+}
+
+// TemplateDump renders every row of r with the text/template given with --template
+// --header-template and --footer-template are rendered once before and after the rows
+func TemplateDump(d *dumper, r io.Reader, w io.Writer, filename string) error {
+	groupSize := d.groupSize
+	if groupSize < 1 {
+		groupSize = d.cols
+	}
+
+	funcs := templateFuncs(d)
+
+	tmpl, err := loadTemplate("row", opts.Template, funcs)
+	if err != nil {
+		return err
+	}
+
+	var header, footer *template.Template
+	if opts.TmplHeader != "" {
+		if header, err = loadTemplate("header", opts.TmplHeader, funcs); err != nil {
+			return err
+		}
+	}
+	if opts.TmplFooter != "" {
+		if footer, err = loadTemplate("footer", opts.TmplFooter, funcs); err != nil {
+			return err
+		}
+	}
+
+	info := templateInfo{File: path.Base(filename), Columns: d.cols}
+
+	if header != nil {
+		if err := header.Execute(w, info); err != nil {
+			return fmt.Errorf("template: %v", err)
+		}
+	}
+
+	var hex []byte
+	err = d.rows(r, func(row *dumpRow) error {
+		hex = hex[:0]
+		for _, v := range row.data {
+			hex = append(hex, d.tables.hex[v][:]...)
+		}
+
+		n := len(row.data)
+		var groups []string
+		for i := 0; i < n; i += groupSize {
+			groups = append(groups, string(hex[i*2:min(i+groupSize, n)*2]))
+		}
+
+		data := templateRow{
+			Index:   info.Rows,
+			Offset:  row.offset,
+			Bytes:   row.data,
+			Hex:     string(hex),
+			ASCII:   row.text,
+			Groups:  groups,
+			Skipped: row.skipped,
+		}
+		if err := tmpl.Execute(w, data); err != nil {
+			return fmt.Errorf("template: %v", err)
+		}
+
+		info.Rows++
+		info.Size += int64(n) + row.skipped
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if footer != nil {
+		if err := footer.Execute(w, info); err != nil {
+			return fmt.Errorf("template: %v", err)
+		}
+	}
+
+	return nil
+}
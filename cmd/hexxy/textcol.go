@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// drawn in the cells of the bytes that belong to the glyph before them
+var continuation = []byte("·")
+
+// decodes the glyph starting at b[0], b may include bytes of the following row
+// size is the number of bytes used, printable is false for bytes that are drawn as '.'
+type textDecoder func(b []byte) (r rune, size int, printable bool)
+
+// renders the text column of the hex dump
+type textColumn struct {
+	decode textDecoder
+	width  int  // the longest sequence the decoder reads
+	unit   int  // the size of a code unit, the text stays aligned to it
+	carry  int  // cells of the next row that continue the last glyph
+	dots   bool // the carried cells belong to an unprintable sequence
+	glyphs bool // draw markers instead of '.'
+	lead   int  // bytes of the first code unit of the next row that were skipped
+
+	// the end of a row joined with the start of the next one
+	tail [2 * utf8.UTFMax]byte
+}
+
+// a code page replaces the ascii decoder, it can not be combined with a unicode encoding
+func newTextColumn(mode, codepage string, glyphs bool) (*textColumn, error) {
+	t := &textColumn{glyphs: glyphs, width: 1, unit: 1}
+
+	if codepage != "" {
+		table, ok := codepages[codepage]
+		if !ok {
+			return nil, fmt.Errorf("unknown code page %q", codepage)
+		}
+		if mode != "" && mode != "ascii" {
+			return nil, fmt.Errorf("--codepage %s can not be used with --text %s", codepage, mode)
+		}
+		t.decode = decodeTable(table)
+		return t, nil
+	}
+
+	switch mode {
+	case "", "ascii":
+		t.decode = decodeASCII
+	case "latin1":
+		t.decode = decodeLatin1
+	case "utf8":
+		t.decode = decodeUTF8
+		t.width = utf8.UTFMax
+	case "utf16le":
+		t.decode = func(b []byte) (rune, int, bool) { return decodeUTF16(b, false) }
+		t.width, t.unit = 4, 2
+	case "utf16be":
+		t.decode = func(b []byte) (rune, int, bool) { return decodeUTF16(b, true) }
+		t.width, t.unit = 4, 2
+	default:
+		return nil, fmt.Errorf("unknown text encoding %q", mode)
+	}
+
+	return t, nil
+}
+
+func decodeASCII(b []byte) (rune, int, bool) {
+	return rune(b[0]), 1, b[0] > 0x1f && b[0] < 0x7f
+}
+
+func decodeLatin1(b []byte) (rune, int, bool) {
+	r := rune(b[0])
+	return r, 1, isGlyph(r)
+}
+
+func decodeUTF8(b []byte) (rune, int, bool) {
+	r, size := utf8.DecodeRune(b)
+	if r == utf8.RuneError && size <= 1 {
+		return r, 1, false
+	}
+	return r, size, isGlyph(r)
+}
+
+func decodeUTF16(b []byte, big bool) (rune, int, bool) {
+	unit := func(b []byte) uint16 {
+		if big {
+			return uint16(b[0])<<8 | uint16(b[1])
+		}
+		return uint16(b[1])<<8 | uint16(b[0])
+	}
+
+	if len(b) < 2 {
+		return 0, 1, false
+	}
+
+	r := rune(unit(b))
+	if utf16.IsSurrogate(r) {
+		if len(b) < 4 {
+			return r, 2, false
+		}
+		r = utf16.DecodeRune(r, rune(unit(b[2:])))
+		if r == unicode.ReplacementChar {
+			return r, 2, false
+		}
+		return r, 4, isGlyph(r)
+	}
+
+	return r, 2, isGlyph(r)
+}
+
+// the marker drawn for an unprintable byte with --glyphs, r is what the byte decoded to
+// control characters get their control picture (␀ ␉ ␊ ␍ ␛), other bytes a marker for their range
+func controlGlyph(b byte, r rune, size int) rune {
+	if size == 1 {
+		switch {
+		case r < 0x20:
+			return 0x2400 + r
+		case r == 0x7f:
+			return '␡'
+		}
+	}
+
+	switch {
+	case b < 0x20:
+		return 0x2400 + rune(b)
+	case b == 0x7f:
+		return '␡'
+	case b == 0xff:
+		return '■'
+	case b >= 0x80:
+		return '×'
+	}
+	return '.'
+}
+
+// control characters, formatting and combining marks would break the layout
+func isGlyph(r rune) bool {
+	return unicode.IsPrint(r) && !unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf)
+}
+
+// glyphs that take up two cells in a terminal
+func isWide(r rune) bool {
+	switch {
+	case r < 0x1100:
+		return false
+	case r <= 0x115f, // Hangul Jamo
+		r >= 0x2e80 && r <= 0xa4cf && r != 0x303f, // CJK, Kana, Yi
+		r >= 0xac00 && r <= 0xd7a3,                // Hangul syllables
+		r >= 0xf900 && r <= 0xfaff,                // CJK compatibility ideographs
+		r >= 0xfe30 && r <= 0xfe4f,                // CJK compatibility forms
+		r >= 0xff00 && r <= 0xff60,                // fullwidth forms
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f, // emoji
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd:
+		return true
+	}
+	return false
+}
+
+// starts over after zeros that weren't drawn, for --autoskip and holes
+// offset is where the next row starts, counted from the start of the dump
+func (t *textColumn) skip(offset int64) {
+	t.carry = 0
+	t.lead = int(offset % int64(t.unit))
+}
+
+// writes the text column for the bytes of a row, next holds the bytes that follow the row
+// so sequences crossing the end of the row can still be decoded, a nil color writes plain text
+func (t *textColumn) render(cw *colorWriter, b, next []byte, color *Color) {
+	if t.lead > 0 {
+		// a code unit that started in the skipped zeros carries into the row,
+		// like it would without skipping
+		var zeros [2]byte
+		lead := t.lead
+		t.lead = 0
+		t.render(&colorWriter{}, zeros[:lead], b, nil)
+	}
+
+	colored := USE_COLOR && !opts.AsciiColor && color != nil
+
+	// text in the grey of the offsets, or plain without color
+	writeGrey := func(s []byte) {
+		if colored {
+			cw.write(GREY, false, s)
+		} else {
+			cw.plain(s)
+		}
+	}
+
+	// a glyph in the color of the byte it starts at
+	writeGlyph := func(v byte, r rune) {
+		var glyph [utf8.UTFMax]byte
+		if colored {
+			cw.byteColor(color, v, glyph[:utf8.EncodeRune(glyph[:], r)])
+		} else {
+			cw.plain(glyph[:utf8.EncodeRune(glyph[:], r)])
+		}
+	}
+
+	// a byte that can not be shown, r and size describe the sequence it starts
+	writeDot := func(v byte, r rune, size int) {
+		if t.glyphs {
+			writeGlyph(v, controlGlyph(v, r, size))
+		} else {
+			writeGrey(dot)
+		}
+	}
+
+	i := 0
+	for ; t.carry > 0 && i < len(b); i++ {
+		if t.dots {
+			writeDot(b[i], utf8.RuneError, 0)
+		} else {
+			writeGrey(continuation)
+		}
+		t.carry--
+	}
+
+	var seq []byte
+
+	for i < len(b) {
+		// only copy when a sequence might run into the next row
+		seq = b[i:]
+		if len(seq) < t.width && len(next) > 0 {
+			n := copy(t.tail[:], seq)
+			n += copy(t.tail[n:], next)
+			seq = t.tail[:n]
+		}
+
+		r, size, printable := t.decode(seq)
+
+		if !printable {
+			// every byte of an invalid or unprintable sequence is its own dot,
+			// the whole sequence is skipped so utf-16 stays aligned
+			writeDot(b[i], r, size)
+			for k := 1; k < size; k++ {
+				if i+k < len(b) {
+					writeDot(b[i+k], utf8.RuneError, 0)
+				} else {
+					t.carry++
+					t.dots = true
+				}
+			}
+			i += size
+			continue
+		}
+
+		if t.glyphs && r == ' ' {
+			r = '␣'
+		}
+
+		// the cells after the glyph get a continuation marker
+		first := 1
+		if isWide(r) {
+			if i+1 < len(b) {
+				first = 2
+			} else {
+				// no room for a wide glyph at the end of the row
+				r = '.'
+			}
+		}
+
+		writeGlyph(b[i], r)
+
+		for k := first; k < size; k++ {
+			if i+k < len(b) {
+				writeGrey(continuation)
+			} else {
+				t.carry++
+				t.dots = false
+			}
+		}
+		i += size
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/ascii85"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+)
+
+// wraps output at a fixed column, a width of 0 disables wrapping
+type lineWriter struct {
+	w     io.Writer
+	width int
+	col   int
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	if l.width <= 0 {
+		return l.w.Write(p)
+	}
+
+	written := 0
+	for len(p) > 0 {
+		n := min(l.width-l.col, len(p))
+		if _, err := l.w.Write(p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		l.col += n
+		p = p[n:]
+
+		if l.col == l.width {
+			if _, err := l.w.Write(newLine); err != nil {
+				return written, err
+			}
+			l.col = 0
+		}
+	}
+	return written, nil
+}
+
+// terminates the last line if it was not already
+func (l *lineWriter) Close() error {
+	if l.col == 0 && l.width > 0 {
+		return nil
+	}
+	_, err := l.w.Write(newLine)
+	return err
+}
+
+// EncodeDump writes r in one of the text encodings selected with --encode
+// base64, base32 and ascii85 are wrapped at --columns (76 by default, 0 to disable)
+func EncodeDump(r io.Reader, w io.Writer, filename string) error {
+	if opts.Encode == "uuencode" {
+		return uuencodeDump(r, w, filename)
+	}
+
+	cols := 76
+	if opts.Columns != -1 {
+		cols = opts.Columns
+	}
+
+	lw := &lineWriter{w: w, width: cols}
+
+	var enc io.WriteCloser
+	switch opts.Encode {
+	case "base64":
+		enc = base64.NewEncoder(base64.StdEncoding, lw)
+	case "base64url":
+		enc = base64.NewEncoder(base64.URLEncoding, lw)
+	case "base32":
+		enc = base32.NewEncoder(base32.StdEncoding, lw)
+	case "ascii85":
+		enc = ascii85.NewEncoder(lw)
+	default:
+		return fmt.Errorf("unknown encoding %q", opts.Encode)
+	}
+
+	if _, err := io.Copy(enc, r); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return lw.Close()
+}
+
+// EncodeReverse decodes text produced by --encode back into binary
+// line breaks and surrounding whitespace are ignored
+func EncodeReverse(r io.Reader, w io.Writer) error {
+	var dec io.Reader
+	switch opts.Encode {
+	case "base64":
+		dec = base64.NewDecoder(base64.StdEncoding, r)
+	case "base64url":
+		dec = base64.NewDecoder(base64.URLEncoding, r)
+	case "base32":
+		dec = base32.NewDecoder(base32.StdEncoding, r)
+	case "ascii85":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		// Adobe style delimiters are optional
+		data = bytes.TrimSpace(data)
+		data = bytes.TrimPrefix(data, []byte("<~"))
+		data = bytes.TrimSuffix(data, []byte("~>"))
+		dec = ascii85.NewDecoder(bytes.NewReader(data))
+	case "uuencode":
+		return uudecodeReverse(r, w)
+	default:
+		return fmt.Errorf("unknown encoding %q", opts.Encode)
+	}
+
+	_, err := io.Copy(w, dec)
+	return err
+}
+
+// uuencode always uses 45 byte lines, the length of each line is encoded in its first character
+func uuencodeDump(r io.Reader, w io.Writer, filename string) error {
+	const lineLen = 45
+
+	var (
+		line = make([]byte, lineLen)
+		out  = make([]byte, 0, 2+lineLen/3*4)
+		err  error
+		n    int
+	)
+
+	fmt.Fprintf(w, "begin 644 %s\n", path.Base(filename))
+
+	r = bufio.NewReader(r)
+	for {
+		n, err = io.ReadFull(r, line)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+
+		out = append(out[:0], uuChar(byte(n)))
+		for i := 0; i < n; i += 3 {
+			var b [3]byte
+			copy(b[:], line[i:n])
+			out = append(out,
+				uuChar(b[0]>>2),
+				uuChar((b[0]<<4|b[1]>>4)&0x3f),
+				uuChar((b[1]<<2|b[2]>>6)&0x3f),
+				uuChar(b[2]&0x3f),
+			)
+		}
+		out = append(out, '\n')
+		w.Write(out)
+	}
+
+	_, err = w.Write([]byte("`\nend\n"))
+	return err
+}
+
+// zero is written as a backtick instead of a space so lines never end in whitespace
+func uuChar(b byte) byte {
+	if b == 0 {
+		return '`'
+	}
+	return b + 32
+}
+
+func uuValue(c byte) byte {
+	return (c - 32) & 0x3f
+}
+
+func uudecodeReverse(r io.Reader, w io.Writer) error {
+	var (
+		rd     = bufio.NewReader(r)
+		begun  bool
+		lineNo int
+		out    []byte
+	)
+
+	for {
+		line, err := rd.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		lineNo++
+
+		line = bytes.TrimRight(line, "\r\n")
+
+		switch {
+		case !begun:
+			begun = bytes.HasPrefix(line, []byte("begin "))
+		case bytes.Equal(line, []byte("end")):
+			return nil
+		case len(line) > 0:
+			n := int(uuValue(line[0]))
+			body := line[1:]
+			if len(body) < (n+2)/3*4 {
+				return fmt.Errorf("uuencode: line %d: line is shorter than its length", lineNo)
+			}
+
+			out = out[:0]
+			for i := 0; len(out) < n; i += 4 {
+				c0, c1, c2, c3 := uuValue(body[i]), uuValue(body[i+1]), uuValue(body[i+2]), uuValue(body[i+3])
+				out = append(out, c0<<2|c1>>4, c1<<4|c2>>2, c2<<6|c3)
+			}
+			if _, werr := w.Write(out[:n]); werr != nil {
+				return werr
+			}
+		}
+
+		if err != nil {
+			if !begun {
+				return fmt.Errorf("uuencode: missing begin line")
+			}
+			return fmt.Errorf("uuencode: missing end line")
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	in := roundTripInput()
+	for _, enc := range []string{"base64", "base64url", "base32", "ascii85", "uuencode"} {
+		for _, cols := range []string{"-1", "0", "7"} {
+			t.Run(enc+"/"+cols, func(t *testing.T) {
+				setFlags(t, "--encode", enc, "-c", cols)
+
+				// every length up to a few uuencode lines, then all of in
+				for n := 0; n <= 100; n++ {
+					data := in[:n]
+					if n == 100 {
+						data = in
+					}
+
+					var text, out bytes.Buffer
+					if err := EncodeDump(bytes.NewReader(data), &text, "in.bin"); err != nil {
+						t.Fatal(err)
+					}
+					if err := EncodeReverse(&text, &out); err != nil {
+						t.Fatalf("%d bytes: %v", len(data), err)
+					}
+					if !bytes.Equal(out.Bytes(), data) {
+						t.Fatalf("%d bytes: the output differs at byte %d", len(data), firstDiff(out.Bytes(), data))
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestEncodeReverse(t *testing.T) {
+	tests := []struct {
+		enc  string
+		in   string
+		want string // the output, or the error
+	}{
+		{"base64", "aGVs\r\nbG8=\r\n", "hello"},
+		{"base64url", "-_8=\n", "\xfb\xff"},
+		{"base32", "NBSWY3DP\n", "hello"},
+		{"ascii85", "<~BOu!rDZ~>\n", "hello"},
+		{"ascii85", "BOu!rDZ", "hello"},
+		{"uuencode", "begin 644 a\n%:&5L;&\\`\n`\nend\n", "hello"},
+		{"base64", "aGVs*G8=\n", "illegal base64 data at input byte 4"},
+		{"base32", "NBSWY3D\n", "unexpected EOF"},
+		{"ascii85", "<~BOu!rD{~>", "illegal ascii85 data at input byte 6"},
+		{"uuencode", "%:&5L;&\\`\n", "uuencode: missing begin line"},
+		{"uuencode", "begin 644 a\n%:&5L;&\\`\n", "uuencode: missing end line"},
+		{"uuencode", "begin 644 a\n%:&5L\nend\n", "uuencode: line 2: line is shorter than its length"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.enc, func(t *testing.T) {
+			setFlags(t, "--encode", tt.enc)
+
+			var out bytes.Buffer
+			err := EncodeReverse(strings.NewReader(tt.in), &out)
+			switch {
+			case err != nil && err.Error() != tt.want:
+				t.Errorf("%q: %v, want %q", tt.in, err, tt.want)
+			case err == nil && out.String() != tt.want:
+				t.Errorf("%q: %q, want %q", tt.in, out.String(), tt.want)
+			}
+		})
+	}
+}
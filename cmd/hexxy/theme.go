@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// the [Theme] section of hexxy.ini, these options can only be set in the config file
+var themeOpts struct {
+	Null       string   `ini-name:"null" description:"style of NUL bytes"`
+	Printable  string   `ini-name:"printable" description:"style of printable ascii"`
+	Whitespace string   `ini-name:"whitespace" description:"style of ascii whitespace"`
+	Other      string   `ini-name:"other" description:"style of other ascii bytes"`
+	NonASCII   string   `ini-name:"non-ascii" description:"style of bytes above 0x7f"`
+	Bytes      []string `ini-name:"byte" description:"style of a single byte or a range, BYTE:STYLE or FIRST-LAST:STYLE (can be repeated)"`
+}
+
+const (
+	colorNone = iota
+	colorPalette
+	colorRGB
+)
+
+// a color of the 256 color palette or a 24-bit color
+type termColor struct {
+	kind    int
+	index   uint8
+	r, g, b uint8
+}
+
+func palette(i uint8) termColor {
+	return termColor{kind: colorPalette, index: i}
+}
+
+func rgb(r, g, b uint8) termColor {
+	return termColor{kind: colorRGB, r: r, g: g, b: b}
+}
+
+// appends the SGR parameters of a color, base is 30 for the foreground and 40 for the background
+func (c termColor) appendSGR(p []string, base int) []string {
+	// downsample to what the terminal can show
+	switch {
+	case c.kind == colorRGB && colorDepth == depth256:
+		c = palette(nearest256(c.r, c.g, c.b))
+	case c.kind == colorRGB && colorDepth == depth16:
+		c = palette(nearest16(c.r, c.g, c.b))
+	case c.kind == colorPalette && c.index >= 16 && colorDepth == depth16:
+		c = palette(nearest16(xtermRGB(int(c.index))))
+	}
+
+	switch c.kind {
+	case colorPalette:
+		// the 16 system colors have their own short codes
+		switch {
+		case c.index < 8:
+			return append(p, strconv.Itoa(base+int(c.index)))
+		case c.index < 16:
+			return append(p, strconv.Itoa(base+60+int(c.index)-8))
+		}
+		return append(p, strconv.Itoa(base+8), "5", strconv.Itoa(int(c.index)))
+	case colorRGB:
+		return append(p, strconv.Itoa(base+8), "2", strconv.Itoa(int(c.r)), strconv.Itoa(int(c.g)), strconv.Itoa(int(c.b)))
+	}
+	return p
+}
+
+// how a byte is drawn
+type style struct {
+	fg, bg termColor
+	bold   bool
+	dim    bool
+}
+
+// the escape sequence that starts the style, empty for the default style
+func (s style) sgr() string {
+	var p []string
+	if s.bold {
+		p = append(p, "1")
+	}
+	if s.dim {
+		p = append(p, "2")
+	}
+	p = s.fg.appendSGR(p, 30)
+	p = s.bg.appendSGR(p, 40)
+
+	if len(p) == 0 {
+		return ""
+	}
+	return "\x1b[" + strings.Join(p, ";") + "m"
+}
+
+// themes that color bytes by category, indexed by byteCategory
+// the gradient theme is computed from the byte values in Color.Compute
+var themes = map[string][5]style{
+	"category": {
+		catNull:       {fg: palette(242)},
+		catPrintable:  {fg: palette(6)},
+		catWhitespace: {fg: palette(2)},
+		catOther:      {fg: palette(5)},
+		catNonASCII:   {fg: palette(3)},
+	},
+	"monochrome-bold": {
+		catNull:      {dim: true},
+		catPrintable: {bold: true},
+	},
+	"solarized": {
+		catNull:       {fg: rgb(0x58, 0x6e, 0x75)},
+		catPrintable:  {fg: rgb(0x2a, 0xa1, 0x98)},
+		catWhitespace: {fg: rgb(0x85, 0x99, 0x00)},
+		catOther:      {fg: rgb(0xd3, 0x36, 0x82)},
+		catNonASCII:   {fg: rgb(0xb5, 0x89, 0x00)},
+	},
+	// the Okabe-Ito palette, distinguishable with every common form of color blindness
+	"colourblind-safe": {
+		catNull:       {fg: rgb(0x99, 0x99, 0x99)},
+		catPrintable:  {fg: rgb(0x56, 0xb4, 0xe9)},
+		catWhitespace: {fg: rgb(0x00, 0x9e, 0x73)},
+		catOther:      {fg: rgb(0xcc, 0x79, 0xa7)},
+		catNonASCII:   {fg: rgb(0xe6, 0x9f, 0x00)},
+	},
+}
+
+// parses a color given as a palette index (0-255) or as #rrggbb
+func parseColor(s string) (termColor, error) {
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		v, err := strconv.ParseUint(hex, 16, 24)
+		if err != nil || len(hex) != 6 {
+			return termColor{}, fmt.Errorf("invalid color %q, expected #rrggbb", s)
+		}
+		return rgb(uint8(v>>16), uint8(v>>8), uint8(v)), nil
+	}
+
+	v, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return termColor{}, fmt.Errorf("invalid color %q, expected 0-255 or #rrggbb", s)
+	}
+	return palette(uint8(v)), nil
+}
+
+// parses a style such as "bold 196", "#2aa198" or "255 on 124"
+// the words are bold, dim, a foreground color and "on" followed by a background color
+func parseStyle(s string) (style, error) {
+	var (
+		st     style
+		fields = strings.Fields(s)
+		err    error
+	)
+
+	for i := 0; i < len(fields); i++ {
+		switch f := fields[i]; f {
+		case "bold":
+			st.bold = true
+		case "dim":
+			st.dim = true
+		case "default", "none":
+		case "on":
+			if i+1 >= len(fields) {
+				return style{}, fmt.Errorf("style %q: missing background color after \"on\"", s)
+			}
+			i++
+			if st.bg, err = parseColor(fields[i]); err != nil {
+				return style{}, fmt.Errorf("style %q: %v", s, err)
+			}
+		default:
+			if st.fg, err = parseColor(f); err != nil {
+				return style{}, fmt.Errorf("style %q: %v", s, err)
+			}
+		}
+	}
+
+	return st, nil
+}
+
+func (c *Color) setStyle(i int, s style) {
+	c.values[i] = s.sgr()
+	c.cvalues[i] = []byte(c.values[i])
+	c.bg[i] = s.bg.kind != colorNone
+}
+
+// computes the colors of a theme and applies the overrides of the [Theme] section
+func (c *Color) LoadTheme(name string) error {
+	if name == "" || name == "gradient" {
+		c.Compute()
+	} else {
+		t, ok := themes[name]
+		if !ok {
+			return fmt.Errorf("unknown theme %q", name)
+		}
+		for i := 0; i < 256; i++ {
+			c.setStyle(i, t[byteCategory(byte(i))])
+		}
+	}
+
+	categories := [...]string{
+		catNull:       themeOpts.Null,
+		catPrintable:  themeOpts.Printable,
+		catWhitespace: themeOpts.Whitespace,
+		catOther:      themeOpts.Other,
+		catNonASCII:   themeOpts.NonASCII,
+	}
+
+	for cat, spec := range categories {
+		if spec == "" {
+			continue
+		}
+		s, err := parseStyle(spec)
+		if err != nil {
+			return fmt.Errorf("theme %s: %v", categoryNames[cat], err)
+		}
+		for i := 0; i < 256; i++ {
+			if byteCategory(byte(i)) == cat {
+				c.setStyle(i, s)
+			}
+		}
+	}
+
+	// single bytes are applied last so they win over their category
+	for _, spec := range themeOpts.Bytes {
+		bytes, st, ok := strings.Cut(spec, ":")
+		if !ok {
+			return fmt.Errorf("theme byte %q: expected BYTE:STYLE", spec)
+		}
+
+		first, last, isRange := strings.Cut(bytes, "-")
+		if !isRange {
+			last = first
+		}
+		lo, err := parseNumber(strings.TrimSpace(first), 8)
+		if err != nil {
+			return fmt.Errorf("theme byte %q: %v", spec, err)
+		}
+		hi, err := parseNumber(strings.TrimSpace(last), 8)
+		if err != nil {
+			return fmt.Errorf("theme byte %q: %v", spec, err)
+		}
+
+		s, err := parseStyle(st)
+		if err != nil {
+			return fmt.Errorf("theme byte %q: %v", spec, err)
+		}
+		for i := lo; i <= hi; i++ {
+			c.setStyle(int(i), s)
+		}
+	}
+
+	c.share()
+	return nil
+}
+
+// makes bytes with the same escape sequence share one slice, so colorWriter
+// can tell that the color did not change without comparing the sequences
+func (c *Color) share() {
+	seen := make(map[string][]byte)
+	for i := range c.cvalues {
+		if s, ok := seen[c.values[i]]; ok {
+			c.cvalues[i] = s
+		} else {
+			seen[c.values[i]] = c.cvalues[i]
+		}
+	}
+}